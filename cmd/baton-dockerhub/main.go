@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/url"
 	"os"
 
 	configschema "github.com/conductorone/baton-sdk/pkg/config"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/conductorone/baton-dockerhub/pkg/config"
 	"github.com/conductorone/baton-dockerhub/pkg/connector"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
 )
 
 var version = "dev"
@@ -38,11 +42,15 @@ func main() {
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
+	clientOpts, err := newClientOptions(v)
+	if err != nil {
+		l.Error("error configuring client", zap.Error(err))
+		return nil, err
+	}
+
 	username := v.GetString(config.Username.FieldName)
-	accessToken := v.GetString(config.AccessToken.FieldName)
 	orgs := v.GetStringSlice(config.Orgs.FieldName)
-	password := v.GetString(config.Password.FieldName)
-	cb, err := connector.New(ctx, username, accessToken, password, orgs)
+	cb, err := connector.New(ctx, username, newAuthenticator(v), orgs, clientOpts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -56,3 +64,83 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 
 	return c, nil
 }
+
+// newAuthenticator picks the authentication method matching the provided credentials.
+func newAuthenticator(v *viper.Viper) dockerhub.Authenticator {
+	username := v.GetString(config.Username.FieldName)
+
+	switch {
+	case v.GetString(config.OrgAccessToken.FieldName) != "":
+		return &dockerhub.OrgAccessTokenAuth{
+			Organization: v.GetString(config.Organization.FieldName),
+			Token:        v.GetString(config.OrgAccessToken.FieldName),
+		}
+	case v.GetString(config.BearerToken.FieldName) != "":
+		return &dockerhub.BearerTokenAuth{
+			Token: v.GetString(config.BearerToken.FieldName),
+		}
+	case v.GetString(config.Password.FieldName) != "":
+		return &dockerhub.PasswordAuth{
+			Username:   username,
+			Password:   v.GetString(config.Password.FieldName),
+			TOTPSecret: v.GetString(config.TOTPSecret.FieldName),
+		}
+	default:
+		return &dockerhub.PersonalAccessTokenAuth{
+			Username: username,
+			Token:    v.GetString(config.AccessToken.FieldName),
+		}
+	}
+}
+
+// newClientOptions configures where the DockerHub API is reached and how its certificate is verified.
+func newClientOptions(v *viper.Viper) ([]dockerhub.Option, error) {
+	var opts []dockerhub.Option
+
+	if rawUrl := v.GetString(config.BaseURL.FieldName); rawUrl != "" {
+		baseUrl, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+		opts = append(opts, dockerhub.WithBaseURL(baseUrl))
+	}
+
+	if rawUrl := v.GetString(config.LoginURL.FieldName); rawUrl != "" {
+		loginUrl, err := url.Parse(rawUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid login URL: %w", err)
+		}
+		opts = append(opts, dockerhub.WithLoginURL(loginUrl))
+	}
+
+	caBundle := v.GetString(config.CABundle.FieldName)
+	insecureSkipVerify := v.GetBool(config.InsecureSkipVerify.FieldName)
+	if caBundle == "" && !insecureSkipVerify {
+		return opts, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec // explicitly opted into for test stand-ins.
+	}
+
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caBundle)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return append(opts, dockerhub.WithTLSConfig(tlsConfig)), nil
+}
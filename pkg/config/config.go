@@ -5,20 +5,41 @@ import (
 )
 
 var (
-	Username    = field.StringField("username", field.WithRequired(true), field.WithDescription("The DockerHub username used to connect to the DockerHub API."))
+	Username    = field.StringField("username", field.WithDescription("The DockerHub username used to connect to the DockerHub API."))
 	AccessToken = field.StringField("access-token", field.WithDescription("The DockerHub Personal Access Token used to connect to the DockerHub API."))
 	Password    = field.StringField("password", field.WithDescription("The DockerHub password used to connect to the DockerHub API."))
+	TOTPSecret  = field.StringField("totp-secret", field.WithDescription("The TOTP secret of the DockerHub account, required with password when two-factor authentication is enabled."))
+	BearerToken = field.StringField("bearer-token", field.WithDescription("A pre-issued DockerHub JWT used as is to connect to the DockerHub API."))
 	Orgs        = field.StringSliceField("orgs", field.WithDescription("Limit syncing to specific organizations by providing organization slugs."))
+
+	BaseURL            = field.StringField("base-url", field.WithDescription("The base URL of the DockerHub API, e.g. of a mock or a gateway in front of it."), field.WithDefaultValue("https://hub.docker.com"))
+	LoginURL           = field.StringField("login-url", field.WithDescription("The base URL of the DockerHub authentication endpoints, when different from the base URL."))
+	CABundle           = field.StringField("ca-bundle", field.WithDescription("Path to a PEM file with additional CA certificates to trust when connecting to the DockerHub API."))
+	InsecureSkipVerify = field.BoolField("insecure-skip-verify", field.WithDescription("Skip verification of the DockerHub API certificate. Only meant for testing against stand-ins."))
+
+	Organization   = field.StringField("organization", field.WithDescription("The DockerHub organization the organization access token belongs to."))
+	OrgAccessToken = field.StringField("org-access-token", field.WithDescription("The DockerHub Organization Access Token used to connect to the DockerHub API."))
 )
 
 var constraints = []field.SchemaFieldRelationship{
-	field.FieldsMutuallyExclusive(AccessToken, Password),
-	field.FieldsAtLeastOneUsed(AccessToken, Password),
+	field.FieldsMutuallyExclusive(AccessToken, Password, BearerToken, OrgAccessToken),
+	field.FieldsAtLeastOneUsed(AccessToken, Password, BearerToken, OrgAccessToken),
+	field.FieldsDependentOn([]field.SchemaField{AccessToken, Password}, []field.SchemaField{Username}),
+	field.FieldsRequiredTogether(Organization, OrgAccessToken),
+	field.FieldsDependentOn([]field.SchemaField{TOTPSecret}, []field.SchemaField{Password}),
 }
 
 var Configuration = field.NewConfiguration([]field.SchemaField{
 	Username,
 	AccessToken,
 	Password,
+	TOTPSecret,
+	BearerToken,
 	Orgs,
+	Organization,
+	OrgAccessToken,
+	BaseURL,
+	LoginURL,
+	CABundle,
+	InsecureSkipVerify,
 }, constraints...)
@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type accessTokenResourceType struct {
+	resourceType *v2.ResourceType
+	client       *dockerhub.Client
+}
+
+func (a *accessTokenResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeAccessToken
+}
+
+// Create a new connector resource for a DockerHub personal access token of the given owner.
+func accessTokenResource(ctx context.Context, token *dockerhub.AccessToken, ownerId *v2.ResourceId) (*v2.Resource, error) {
+	scopes := make([]interface{}, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		scopes = append(scopes, scope)
+	}
+
+	profile := map[string]interface{}{
+		"token_id":     token.Uuid,
+		"label":        token.Label,
+		"scopes":       scopes,
+		"is_active":    token.IsActive,
+		"generated_by": token.GeneratedBy,
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+		rs.WithSecretCreatedByID(ownerId),
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, token.CreatedAt); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedAt(createdAt))
+	}
+
+	if lastUsedAt, err := time.Parse(time.RFC3339, token.LastUsed); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretLastUsedAt(lastUsedAt))
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretExpiresAt(expiresAt))
+	}
+
+	displayName := token.Label
+	if displayName == "" {
+		displayName = token.Uuid
+	}
+
+	resource, err := rs.NewSecretResource(
+		displayName,
+		resourceTypeAccessToken,
+		token.Uuid,
+		secretTraitOptions,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the personal access tokens of the user the connector runs as. Organization access tokens
+// don't belong to a user, and DockerHub doesn't let sessions started with a personal access token list
+// access tokens, so in both cases there is nothing to sync.
+func (a *accessTokenResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId != nil || a.client.ScopedOrganization() != "" {
+		return nil, "", nil, nil
+	}
+
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: resourceTypeAccessToken.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	paginationOpts := dockerhub.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	tokens, nextPage, rateLimitData, err := a.client.ListAccessTokens(ctx, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		if status.Code(err) == codes.PermissionDenied {
+			ctxzap.Extract(ctx).Warn("dockerhub-connector: not allowed to list personal access tokens, skipping")
+			return nil, "", annos, nil
+		}
+
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list access tokens: %w", err)
+	}
+
+	next, err := bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, next, annos, nil
+	}
+
+	owner, rateLimitData, err := a.client.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get current user: %w", err)
+	}
+
+	ownerId := &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: owner.Id}
+
+	var rv []*v2.Resource
+	for _, token := range tokens {
+		tokenCopy := token
+
+		tr, err := accessTokenResource(ctx, &tokenCopy, ownerId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, tr)
+	}
+
+	ctxzap.Extract(ctx).Debug("dockerhub-connector: listed personal access tokens", zap.String("user", owner.Username), zap.Int("count", len(rv)))
+
+	return rv, next, annos, nil
+}
+
+// Entitlements always returns an empty slice for personal access tokens.
+func (a *accessTokenResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for personal access tokens, their owner is part of the secret trait.
+func (a *accessTokenResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func accessTokenBuilder(client *dockerhub.Client) *accessTokenResourceType {
+	return &accessTokenResourceType{
+		resourceType: resourceTypeAccessToken,
+		client:       client,
+	}
+}
@@ -0,0 +1,68 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestAccessTokenList(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	fixture := testFixture()
+	fixture.Users[0].AccessTokens = append(fixture.Users[0].AccessTokens, dockerhubtest.AccessToken{
+		Token:     "dckr_pat_old",
+		Label:     "laptop",
+		Scopes:    []string{"repo:admin"},
+		Inactive:  true,
+		ExpiresAt: expiresAt,
+	})
+	server := dockerhubtest.NewServer(t, fixture)
+	a := accessTokenBuilder(newTestConnector(ctx, t, server).client)
+
+	resources, _, _, err := a.List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 access tokens, got %d", len(resources))
+	}
+
+	for _, r := range resources {
+		if owner := secretTrait(t, r).CreatedById.GetResource(); owner != server.UserID("alice") {
+			t.Errorf("expected %s to be owned by alice, got %s", r.DisplayName, owner)
+		}
+	}
+
+	laptop := secretTrait(t, resources[1])
+	if !laptop.ExpiresAt.AsTime().Equal(expiresAt) {
+		t.Errorf("expected laptop to expire at %v, got %v", expiresAt, laptop.ExpiresAt.AsTime())
+	}
+	if laptop.Profile.Fields["is_active"].GetBoolValue() {
+		t.Error("expected laptop to be inactive")
+	}
+}
+
+func TestAccessTokenListSkipsPersonalAccessTokenSessions(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "alice", &dockerhub.PersonalAccessTokenAuth{Username: "alice", Token: "dckr_pat_alice"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources, _, _, err := accessTokenBuilder(dh.client).List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resources) != 0 {
+		t.Errorf("expected no access tokens, got %v", resources)
+	}
+}
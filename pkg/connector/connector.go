@@ -11,6 +11,7 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 )
 
 type DockerHub struct {
@@ -22,9 +23,13 @@ type DockerHub struct {
 func (dh *DockerHub) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
 		orgBuilder(dh.client, dh.orgs),
-		repositoryBuilder(dh.client),
-		userBuilder(dh.client),
-		teamBuilder(dh.client),
+		namespaceBuilder(dh.client),
+		repositoryBuilder(dh.client, dh.orgs),
+		userBuilder(dh.client, dh.orgs),
+		teamBuilder(dh.client, dh.orgs),
+		invitationBuilder(dh.client),
+		orgAccessTokenBuilder(dh.client, dh.orgs),
+		accessTokenBuilder(dh.client),
 	}
 }
 
@@ -45,8 +50,12 @@ func (dh *DockerHub) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
 func (dh *DockerHub) Validate(ctx context.Context) (annotations.Annotations, error) {
+	if orgSlug := dh.client.ScopedOrganization(); orgSlug != "" {
+		return nil, dh.validateOrgAccessToken(ctx, orgSlug)
+	}
+
 	// get the scope of used credentials
-	_, _, err := dh.client.ListOrganizations(ctx, nil)
+	_, _, _, err := dh.client.ListOrganizations(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("dockerhub-connector: validate: failed to list organizations: %w", err)
 	}
@@ -54,20 +63,66 @@ func (dh *DockerHub) Validate(ctx context.Context) (annotations.Annotations, err
 	return nil, nil
 }
 
+// validateOrgAccessToken checks that the organization access token is allowed to read
+// everything synced from its organization.
+func (dh *DockerHub) validateOrgAccessToken(ctx context.Context, orgSlug string) error {
+	if len(dh.orgs) != 0 && !slices.Contains(dh.orgs, orgSlug) {
+		return fmt.Errorf("dockerhub-connector: validate: organization access token is scoped to %s, which is not one of the synced organizations", orgSlug)
+	}
+
+	_, _, err := dh.client.GetOrganization(ctx, orgSlug)
+	if err != nil {
+		return fmt.Errorf("dockerhub-connector: validate: organization access token can't access organization %s: %w", orgSlug, err)
+	}
+
+	pVars := &dockerhub.PaginationVars{Size: 1}
+
+	_, _, _, err = dh.client.ListUsers(ctx, orgSlug, pVars)
+	if err != nil {
+		return fmt.Errorf("dockerhub-connector: validate: organization access token can't read members of %s: %w", orgSlug, err)
+	}
+
+	_, _, _, err = dh.client.ListTeams(ctx, orgSlug, pVars)
+	if err != nil {
+		return fmt.Errorf("dockerhub-connector: validate: organization access token can't read teams of %s: %w", orgSlug, err)
+	}
+
+	_, _, _, err = dh.client.ListRepositories(ctx, orgSlug, pVars)
+	if err != nil {
+		return fmt.Errorf("dockerhub-connector: validate: organization access token can't read repositories of %s: %w", orgSlug, err)
+	}
+
+	return nil
+}
+
 // New returns a new instance of the connector.
-func New(ctx context.Context, username, accessToken, password string, orgs []string) (*DockerHub, error) {
+func New(ctx context.Context, username string, auth dockerhub.Authenticator, orgs []string, opts ...dockerhub.Option) (*DockerHub, error) {
 	l := ctxzap.Extract(ctx)
 	l.Debug("creating client")
-	hubClient, err := dockerhub.NewClient(ctx, username, password, accessToken)
+	hubClient, err := dockerhub.NewClient(ctx, auth, opts...)
 	if err != nil {
 		l.Error("error creating client", zap.Error(err))
 		return nil, err
 	}
 
-	err = hubClient.SetCurrentUser(ctx, username)
-	if err != nil {
-		l.Error("error setting current user", zap.Error(err))
-		return nil, err
+	// organization access tokens are not tied to any user
+	if hubClient.ScopedOrganization() == "" {
+		// bearer tokens don't come with a username, ask the API who we are
+		if username == "" {
+			user, _, err := hubClient.GetCurrentUser(ctx)
+			if err != nil {
+				l.Error("error getting current user", zap.Error(err))
+				return nil, err
+			}
+
+			username = user.Username
+		}
+
+		err = hubClient.SetCurrentUser(ctx, username)
+		if err != nil {
+			l.Error("error setting current user", zap.Error(err))
+			return nil, err
+		}
 	}
 
 	return &DockerHub{
@@ -0,0 +1,472 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	"github.com/conductorone/baton-sdk/pkg/sync"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// connectorClient combines the clients of all connector services, the shape
+// the syncer expects.
+type connectorClient struct {
+	v2.ResourceTypesServiceClient
+	v2.ResourcesServiceClient
+	v2.EntitlementsServiceClient
+	v2.GrantsServiceClient
+	v2.ConnectorServiceClient
+	v2.AssetServiceClient
+	v2.GrantManagerServiceClient
+	v2.ResourceManagerServiceClient
+	v2.AccountManagerServiceClient
+	v2.CredentialManagerServiceClient
+	v2.EventServiceClient
+	v2.TicketsServiceClient
+}
+
+// serveConnector serves the connector over gRPC on a loopback port, the same
+// way baton runs connectors, and returns a client of it.
+func serveConnector(ctx context.Context, t *testing.T, dh *DockerHub) types.ConnectorClient {
+	t.Helper()
+
+	cs, err := connectorbuilder.NewConnector(ctx, dh)
+	if err != nil {
+		t.Fatalf("failed to create connector server: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	v2.RegisterResourceTypesServiceServer(server, cs)
+	v2.RegisterResourcesServiceServer(server, cs)
+	v2.RegisterEntitlementsServiceServer(server, cs)
+	v2.RegisterGrantsServiceServer(server, cs)
+	v2.RegisterConnectorServiceServer(server, cs)
+	v2.RegisterAssetServiceServer(server, cs)
+	v2.RegisterGrantManagerServiceServer(server, cs)
+	v2.RegisterResourceManagerServiceServer(server, cs)
+	v2.RegisterAccountManagerServiceServer(server, cs)
+	v2.RegisterCredentialManagerServiceServer(server, cs)
+	v2.RegisterEventServiceServer(server, cs)
+	v2.RegisterTicketsServiceServer(server, cs)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return &connectorClient{
+		ResourceTypesServiceClient:     v2.NewResourceTypesServiceClient(conn),
+		ResourcesServiceClient:         v2.NewResourcesServiceClient(conn),
+		EntitlementsServiceClient:      v2.NewEntitlementsServiceClient(conn),
+		GrantsServiceClient:            v2.NewGrantsServiceClient(conn),
+		ConnectorServiceClient:         v2.NewConnectorServiceClient(conn),
+		AssetServiceClient:             v2.NewAssetServiceClient(conn),
+		GrantManagerServiceClient:      v2.NewGrantManagerServiceClient(conn),
+		ResourceManagerServiceClient:   v2.NewResourceManagerServiceClient(conn),
+		AccountManagerServiceClient:    v2.NewAccountManagerServiceClient(conn),
+		CredentialManagerServiceClient: v2.NewCredentialManagerServiceClient(conn),
+		EventServiceClient:             v2.NewEventServiceClient(conn),
+		TicketsServiceClient:           v2.NewTicketsServiceClient(conn),
+	}
+}
+
+// syncC1Z runs a full sync of the connector and opens the resulting c1z.
+func syncC1Z(ctx context.Context, t *testing.T, dh *DockerHub) *dotc1z.C1File {
+	t.Helper()
+
+	dir := t.TempDir()
+	c1zPath := filepath.Join(dir, "sync.c1z")
+
+	syncer, err := sync.NewSyncer(ctx, serveConnector(ctx, t, dh), sync.WithC1ZPath(c1zPath), sync.WithTmpDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = syncer.Sync(ctx)
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	err = syncer.Close(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1z, err := dotc1z.NewC1ZFile(ctx, c1zPath, dotc1z.WithTmpDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c1z.Close() })
+
+	return c1z
+}
+
+// syncedResources returns the "type:id" of all synced resources.
+func syncedResources(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) []string {
+	t.Helper()
+
+	var rv []string
+	pageToken := ""
+	for {
+		resp, err := c1z.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range resp.List {
+			rv = append(rv, fmt.Sprintf("%s:%s", r.Id.ResourceType, r.Id.Resource))
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	slices.Sort(rv)
+	return rv
+}
+
+// syncedGrants returns the "entitlement -> principal type:id" of all synced grants.
+func syncedGrants(ctx context.Context, t *testing.T, c1z *dotc1z.C1File) []string {
+	t.Helper()
+
+	var rv []string
+	pageToken := ""
+	for {
+		resp, err := c1z.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, g := range resp.List {
+			rv = append(rv, fmt.Sprintf("%s -> %s:%s", g.Entitlement.Id, g.Principal.Id.ResourceType, g.Principal.Id.Resource))
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	slices.Sort(rv)
+	return rv
+}
+
+func assertContains(t *testing.T, kind string, got []string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		if !slices.Contains(got, w) {
+			t.Errorf("expected %s %q to be synced, got %q", kind, w, got)
+		}
+	}
+}
+
+func assertNotContains(t *testing.T, kind string, got []string, unwanted ...string) {
+	t.Helper()
+
+	for _, u := range unwanted {
+		if slices.Contains(got, u) {
+			t.Errorf("expected %s %q not to be synced", kind, u)
+		}
+	}
+}
+
+// extraMembers is large enough for the members of acme to span several pages.
+const extraMembers = ResourcesPageSize + 5
+
+func testFixture() dockerhubtest.Fixture {
+	fixture := dockerhubtest.Fixture{
+		Users: []dockerhubtest.User{
+			{
+				Username:     "alice",
+				FullName:     "Alice Liddell",
+				Email:        "alice@example.com",
+				Password:     "wonderland",
+				AccessTokens: []dockerhubtest.AccessToken{{Token: "dckr_pat_alice", Label: "baton", Scopes: []string{"repo:read"}}},
+				Repositories: []dockerhubtest.Repository{
+					{Name: "dotfiles", Private: true, Collaborators: map[string]string{"bob": "read", "dave": "write"}},
+				},
+			},
+			{Username: "bob", FullName: "Bob Builder", Email: "bob@example.com"},
+			{Username: "carol", FullName: "Carol Danvers", Email: "carol@example.com"},
+			// only collaborates on a personal repository of alice
+			{Username: "dave", FullName: "Dave Lister", Email: "dave@example.com"},
+		},
+		Organizations: []dockerhubtest.Organization{
+			{
+				Name: "acme",
+				Members: []dockerhubtest.Member{
+					{Username: "alice", Role: "owner"},
+					{Username: "bob", Role: "member"},
+					{Username: "carol", Role: "editor"},
+				},
+				Teams: []dockerhubtest.Team{
+					{Name: "developers", Description: "All developers", Members: []string{"alice", "bob"}},
+					{Name: "ops", Members: []string{"carol"}},
+				},
+				Repositories: []dockerhubtest.Repository{
+					{Name: "api", Description: "The API", Teams: map[string]string{"developers": "write", "ops": "admin"}},
+					{Name: "web", Teams: map[string]string{"developers": "read"}},
+				},
+				AccessTokens: []dockerhubtest.OrgAccessToken{
+					{
+						Token:        "dckr_oat_acme",
+						Label:        "ci",
+						CreatedBy:    "alice",
+						Repositories: map[string][]string{"acme/api": {"repo-pull", "repo-push"}, "acme/web": {"repo-pull"}},
+					},
+				},
+			},
+			{
+				Name: "globex",
+				Members: []dockerhubtest.Member{
+					{Username: "alice", Role: "member"},
+				},
+				Repositories: []dockerhubtest.Repository{
+					{Name: "site"},
+				},
+			},
+		},
+	}
+
+	for i := 0; i < int(extraMembers); i++ {
+		username := fmt.Sprintf("user%02d", i)
+		fixture.Users = append(fixture.Users, dockerhubtest.User{Username: username})
+		fixture.Organizations[0].Members = append(fixture.Organizations[0].Members, dockerhubtest.Member{Username: username, Role: "member"})
+	}
+
+	return fixture
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Organizations[0].Invites = []dockerhubtest.Invite{{Invitee: "erin@example.com", Role: "editor", Team: "developers"}}
+	fixture.Organizations[1].Repositories = append(fixture.Organizations[1].Repositories, dockerhubtest.Repository{Name: "api"})
+	server := dockerhubtest.NewServer(t, fixture)
+
+	dh, err := New(ctx, "alice", &dockerhub.PersonalAccessTokenAuth{Username: "alice", Token: "dckr_pat_alice"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	if _, err := dh.Validate(ctx); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+
+	c1z := syncC1Z(ctx, t, dh)
+
+	developers := server.TeamID("acme", "developers")
+	ops := server.TeamID("acme", "ops")
+	alice, bob, carol, dave := server.UserID("alice"), server.UserID("bob"), server.UserID("carol"), server.UserID("dave")
+	erin := server.InviteID("acme", "erin@example.com")
+	ci := server.OrgAccessTokenID("acme", "ci")
+
+	resources := syncedResources(ctx, t, c1z)
+	assertContains(t, "resource", resources,
+		"org:acme",
+		"org:globex",
+		"user:"+alice,
+		"user:"+bob,
+		"user:"+carol,
+		"user:"+dave,
+		"user:"+server.UserID(fmt.Sprintf("user%02d", extraMembers-1)),
+		fmt.Sprintf("team:%d", developers),
+		fmt.Sprintf("team:%d", ops),
+		"repository:acme/api",
+		"repository:acme/web",
+		"repository:globex/site",
+		// repositories of the same name in different organizations don't collide
+		"repository:globex/api",
+		"namespace:alice",
+		"repository:alice/dotfiles",
+		"invitation:"+erin,
+		"org_access_token:"+ci,
+	)
+
+	grants := syncedGrants(ctx, t, c1z)
+	assertContains(t, "grant", grants,
+		"org:acme:owner -> user:"+alice,
+		"org:acme:member -> user:"+bob,
+		"org:acme:editor -> user:"+carol,
+		"org:acme:member -> user:"+server.UserID(fmt.Sprintf("user%02d", extraMembers-1)),
+		"org:globex:member -> user:"+alice,
+		"org:acme:editor -> invitation:"+erin,
+		fmt.Sprintf("team:%d:member -> user:%s", developers, alice),
+		fmt.Sprintf("team:%d:member -> user:%s", developers, bob),
+		fmt.Sprintf("team:%d:member -> user:%s", ops, carol),
+		fmt.Sprintf("repository:acme/api:write -> team:%d", developers),
+		fmt.Sprintf("repository:acme/api:admin -> team:%d", ops),
+		fmt.Sprintf("repository:acme/web:read -> team:%d", developers),
+		// team grants on repositories are expanded to the members of the team
+		"repository:acme/api:write -> user:"+bob,
+		"repository:acme/api:admin -> user:"+carol,
+		"repository:acme/api:write -> org_access_token:"+ci,
+		"repository:acme/web:read -> org_access_token:"+ci,
+		"namespace:alice:owner -> user:"+alice,
+		"repository:alice/dotfiles:read -> user:"+bob,
+		"repository:alice/dotfiles:write -> user:"+dave,
+	)
+	assertNotContains(t, "grant", grants,
+		"org:acme:owner -> user:"+bob,
+		fmt.Sprintf("team:%d:member -> user:%s", ops, alice),
+		fmt.Sprintf("repository:acme/web:read -> team:%d", ops),
+		fmt.Sprintf("repository:globex/api:write -> team:%d", developers),
+	)
+}
+
+func TestSyncKeepsMemberEmails(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "alice", &dockerhub.PersonalAccessTokenAuth{Username: "alice", Token: "dckr_pat_alice"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	c1z := syncC1Z(ctx, t, dh)
+
+	emails := map[string][]string{}
+	pageToken := ""
+	for {
+		resp, err := c1z.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{ResourceTypeId: resourceTypeUser.Id, PageToken: pageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range resp.List {
+			userTrait, err := rs.GetUserTrait(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, email := range userTrait.Emails {
+				emails[r.Id.Resource] = append(emails[r.Id.Resource], email.Address)
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	// bob and alice are members of acme and collaborator and owner of the personal namespace,
+	// whose public profiles lack the email the member records of the organization carry
+	for _, username := range []string{"alice", "bob"} {
+		want := username + "@example.com"
+		if got := emails[server.UserID(username)]; !slices.Contains(got, want) {
+			t.Errorf("expected %s to keep the email %s, got %v", username, want, got)
+		}
+	}
+}
+
+func TestSyncOrgFilter(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "alice", &dockerhub.PasswordAuth{Username: "alice", Password: "wonderland"}, []string{"globex"}, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	resources := syncedResources(ctx, t, syncC1Z(ctx, t, dh))
+	assertContains(t, "resource", resources, "org:globex", "repository:globex/site")
+	assertNotContains(t, "resource", resources, "org:acme", "repository:acme/api")
+}
+
+func TestSyncWithOrgAccessToken(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "", &dockerhub.OrgAccessTokenAuth{Organization: "acme", Token: "dckr_oat_acme"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	if _, err := dh.Validate(ctx); err != nil {
+		t.Fatalf("validation failed: %v", err)
+	}
+
+	c1z := syncC1Z(ctx, t, dh)
+
+	resources := syncedResources(ctx, t, c1z)
+	assertContains(t, "resource", resources, "org:acme", "repository:acme/api")
+	assertNotContains(t, "resource", resources, "org:globex", "repository:globex/site", "namespace:alice", "repository:alice/dotfiles")
+
+	assertContains(t, "grant", syncedGrants(ctx, t, c1z), "org:acme:owner -> user:"+server.UserID("alice"))
+}
+
+func TestSyncRecoversFromExpiredTokenAndRateLimits(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "alice", &dockerhub.PasswordAuth{Username: "alice", Password: "wonderland"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	server.ExpireTokens()
+	server.Fail(dockerhubtest.Failure{
+		Method: http.MethodGet,
+		Path:   "/v2/orgs/acme/groups",
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{"Retry-After": {"0"}},
+		Times:  2,
+	})
+
+	c1z := syncC1Z(ctx, t, dh)
+
+	assertContains(t, "grant", syncedGrants(ctx, t, c1z),
+		fmt.Sprintf("team:%d:member -> user:%s", server.TeamID("acme", "developers"), server.UserID("bob")),
+	)
+}
+
+func TestValidateRejectsOrgAccessTokenOfOtherOrg(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	dh, err := New(ctx, "", &dockerhub.OrgAccessTokenAuth{Organization: "acme", Token: "dckr_oat_acme"}, []string{"globex"}, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	if _, err := dh.Validate(ctx); err == nil {
+		t.Fatal("expected validation to fail")
+	}
+}
+
+func TestNewRejectsInvalidCredentials(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	_, err := New(ctx, "alice", &dockerhub.PasswordAuth{Username: "alice", Password: "wrong"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err == nil {
+		t.Fatal("expected creating the connector to fail")
+	}
+}
@@ -0,0 +1,360 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Audit log actions translated to events, along with the data DockerHub logs for them.
+const (
+	// data: member, role
+	auditOrgMemberAdd = "org.member.add"
+	// data: member, role
+	auditOrgMemberRemove = "org.member.remove"
+	// data: member, role, previous_role
+	auditOrgMemberRoleChange = "org.member.role.change"
+	// name: org/team, data: member
+	auditTeamMemberAdd    = "team.member.add"
+	auditTeamMemberRemove = "team.member.remove"
+	// name: org/repo, data: team, permission, previous_permission
+	auditRepoPermissionChange = "repo.team.permission.change"
+	// name: org/repo, data: team, permission
+	auditRepoPermissionRemove = "repo.team.permission.remove"
+	// name: org/repo
+	auditRepoCreate = "repo.create"
+	auditRepoDelete = "repo.delete"
+)
+
+const maxEventsPageSize = 100
+
+// eventCursor tracks the position in the audit logs of the synced organizations. Once all of them
+// are read, the feed starts over with the organizations, from the newest event seen so far.
+type eventCursor struct {
+	Org    int       `json:"org"`
+	Page   int       `json:"page"`
+	From   time.Time `json:"from"`
+	Latest time.Time `json:"latest"`
+}
+
+// ListEvents translates the audit logs of the synced organizations to events. Changes of organization roles,
+// teams and repository permissions become grant and revoke events, creating and deleting repositories usage events.
+func (dh *DockerHub) ListEvents(ctx context.Context, earliestEvent *timestamppb.Timestamp, pToken *pagination.StreamToken) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	cursor := eventCursor{Page: 1}
+	if earliestEvent != nil {
+		cursor.From = earliestEvent.AsTime()
+	}
+
+	pageSize := maxEventsPageSize
+	if pToken != nil {
+		if pToken.Cursor != "" {
+			if err := json.Unmarshal([]byte(pToken.Cursor), &cursor); err != nil {
+				return nil, nil, nil, fmt.Errorf("dockerhub-connector: invalid event cursor: %w", err)
+			}
+		}
+
+		if pToken.Size > 0 && pToken.Size < pageSize {
+			pageSize = pToken.Size
+		}
+	}
+
+	orgSlugs, err := syncedOrganizations(ctx, dh.client, dh.orgs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(orgSlugs) == 0 {
+		l.Debug("dockerhub-connector: no organizations to read audit logs of")
+		return nil, &pagination.StreamState{HasMore: false}, nil, nil
+	}
+
+	// the synced organizations changed since the cursor was issued
+	if cursor.Org >= len(orgSlugs) {
+		cursor.Org, cursor.Page = 0, 1
+	}
+
+	orgSlug := orgSlugs[cursor.Org]
+	logs, rateLimitData, err := dh.client.ListAuditLogs(ctx, orgSlug, cursor.From, &dockerhub.PaginationVars{
+		Size: uint(pageSize),
+		Page: strconv.Itoa(cursor.Page),
+	})
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		// only owners can read the audit log
+		if status.Code(err) != codes.PermissionDenied {
+			return nil, nil, annos, fmt.Errorf("dockerhub-connector: failed to list audit logs of %s: %w", orgSlug, err)
+		}
+
+		l.Warn("dockerhub-connector: not allowed to read audit logs of organization, skipping", zap.String("org", orgSlug))
+		logs = nil
+	}
+
+	translator := &eventTranslator{client: dh.client, userIds: map[string]string{}}
+
+	var events []*v2.Event
+	for _, log := range logs {
+		occurredAt, err := time.Parse(time.RFC3339Nano, log.Timestamp)
+		if err != nil {
+			l.Debug("dockerhub-connector: skipping audit log with invalid timestamp", zap.String("timestamp", log.Timestamp))
+			continue
+		}
+
+		if occurredAt.After(cursor.Latest) {
+			cursor.Latest = occurredAt
+		}
+
+		logEvents, err := translator.events(ctx, orgSlug, &log, occurredAt)
+		if err != nil {
+			return nil, nil, annos, err
+		}
+
+		events = append(events, logEvents...)
+	}
+
+	hasMore := true
+	switch {
+	case len(logs) == pageSize:
+		cursor.Page++
+	case cursor.Org+1 < len(orgSlugs):
+		cursor.Org, cursor.Page = cursor.Org+1, 1
+	default:
+		// caught up, continue right after the newest event next time
+		cursor.Org, cursor.Page = 0, 1
+		if cursor.Latest.After(cursor.From) {
+			cursor.From = cursor.Latest.Add(time.Millisecond)
+		}
+		hasMore = false
+	}
+
+	next, err := json.Marshal(cursor)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	return events, &pagination.StreamState{Cursor: string(next), HasMore: hasMore}, annos, nil
+}
+
+// eventTranslator resolves the usernames and team names of audit logs to resource IDs,
+// remembering the users for the page of the audit log. Teams are cached by the client.
+type eventTranslator struct {
+	client  *dockerhub.Client
+	userIds map[string]string
+}
+
+// events translates the audit log to events, none if the action isn't one events are emitted for,
+// or the user or team it affects doesn't exist anymore. Changing a role or permission revokes the
+// previous one before granting the new one, in two events.
+func (t *eventTranslator) events(ctx context.Context, orgSlug string, log *dockerhub.AuditLog, occurredAt time.Time) ([]*v2.Event, error) {
+	event := &v2.Event{
+		Id:         auditLogId(log),
+		OccurredAt: timestamppb.New(occurredAt),
+	}
+
+	// the revoke of the previous role or permission, if it changed
+	var previous *v2.Event
+	revokePrevious := func(resource *v2.Resource, entitlementSlug string, principal *v2.Resource) {
+		previous = &v2.Event{
+			Id:         event.Id + "-previous",
+			OccurredAt: event.OccurredAt,
+			Event:      revokeEvent(resource, entitlementSlug, principal),
+		}
+	}
+
+	org := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgSlug}}
+
+	switch log.Action {
+	case auditOrgMemberAdd, auditOrgMemberRoleChange, auditOrgMemberRemove:
+		role := strings.ToLower(log.Data["role"])
+		if role == "" {
+			role = roleMember
+		}
+		if !slices.Contains(userRoles, role) {
+			return nil, nil
+		}
+
+		member, err := t.user(ctx, log.Data["member"])
+		if member == nil || err != nil {
+			return nil, err
+		}
+
+		if log.Action == auditOrgMemberRemove {
+			event.Event = revokeEvent(org, role, member)
+		} else {
+			event.Event = &v2.Event_GrantEvent{GrantEvent: &v2.GrantEvent{Grant: grant.NewGrant(org, role, member)}}
+		}
+
+		previousRole := strings.ToLower(log.Data["previous_role"])
+		if log.Action == auditOrgMemberRoleChange && previousRole != role && slices.Contains(userRoles, previousRole) {
+			revokePrevious(org, previousRole, member)
+		}
+
+	case auditTeamMemberAdd, auditTeamMemberRemove:
+		team, err := t.team(ctx, orgSlug, log.Name)
+		if team == nil || err != nil {
+			return nil, err
+		}
+
+		member, err := t.user(ctx, log.Data["member"])
+		if member == nil || err != nil {
+			return nil, err
+		}
+
+		if log.Action == auditTeamMemberRemove {
+			event.Event = revokeEvent(team, teamMembership, member)
+		} else {
+			event.Event = &v2.Event_GrantEvent{GrantEvent: &v2.GrantEvent{Grant: grant.NewGrant(team, teamMembership, member)}}
+		}
+
+	case auditRepoPermissionChange, auditRepoPermissionRemove:
+		permission := strings.ToLower(log.Data["permission"])
+		if !slices.Contains(repoPermissions, permission) {
+			return nil, nil
+		}
+
+		team, err := t.team(ctx, orgSlug, orgSlug+"/"+log.Data["team"])
+		if team == nil || err != nil {
+			return nil, err
+		}
+
+		repository := auditLogRepository(org, log)
+		if log.Action == auditRepoPermissionRemove {
+			event.Event = revokeEvent(repository, permission, team)
+		} else {
+			event.Event = &v2.Event_GrantEvent{GrantEvent: &v2.GrantEvent{Grant: grant.NewGrant(repository, permission, team)}}
+		}
+
+		// a team holds a single permission per repository
+		previousPermission := strings.ToLower(log.Data["previous_permission"])
+		if log.Action == auditRepoPermissionChange && previousPermission != permission && slices.Contains(repoPermissions, previousPermission) {
+			revokePrevious(repository, previousPermission, team)
+		}
+
+	case auditRepoCreate, auditRepoDelete:
+		actor, err := t.user(ctx, log.Actor)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Event = &v2.Event_UsageEvent{UsageEvent: &v2.UsageEvent{
+			TargetResource: auditLogRepository(org, log),
+			ActorResource:  actor,
+		}}
+
+	default:
+		return nil, nil
+	}
+
+	if previous != nil {
+		return []*v2.Event{previous, event}, nil
+	}
+
+	return []*v2.Event{event}, nil
+}
+
+// user returns the resource of the user with the given username, or nil if there is no such user anymore.
+func (t *eventTranslator) user(ctx context.Context, username string) (*v2.Resource, error) {
+	if username == "" {
+		return nil, nil
+	}
+
+	id, ok := t.userIds[username]
+	if !ok {
+		user, _, err := t.client.GetUser(ctx, username)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return nil, fmt.Errorf("dockerhub-connector: failed to get user %s: %w", username, err)
+		}
+
+		if user != nil {
+			id = user.Id
+		}
+		t.userIds[username] = id
+	}
+
+	if id == "" {
+		return nil, nil
+	}
+
+	return &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: id}}, nil
+}
+
+// team returns the resource of the team with the given org/team path, or nil if the team was deleted since.
+func (t *eventTranslator) team(ctx context.Context, orgSlug, path string) (*v2.Resource, error) {
+	teamSlug := strings.TrimPrefix(path, orgSlug+"/")
+
+	id, _, err := t.client.ResolveTeamID(ctx, orgSlug, teamSlug)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("dockerhub-connector: failed to get team %s: %w", teamSlug, err)
+	}
+
+	return &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: strconv.Itoa(id)},
+		ParentResourceId: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgSlug},
+	}, nil
+}
+
+func revokeEvent(resource *v2.Resource, entitlementSlug string, principal *v2.Resource) *v2.Event_RevokeEvent {
+	return &v2.Event_RevokeEvent{RevokeEvent: &v2.RevokeEvent{
+		Entitlement: &v2.Entitlement{
+			Id:       ent.NewEntitlementID(resource, entitlementSlug),
+			Resource: resource,
+			Slug:     entitlementSlug,
+		},
+		Principal: principal,
+	}}
+}
+
+// auditLogRepository returns the resource of the repository named org/repo by the audit log.
+func auditLogRepository(org *v2.Resource, log *dockerhub.AuditLog) *v2.Resource {
+	repoSlug := strings.TrimPrefix(log.Name, org.Id.Resource+"/")
+
+	return &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: repositoryId(org.Id.Resource, repoSlug)},
+		ParentResourceId: org.Id,
+	}
+}
+
+// auditLogId derives an ID for the audit log, which DockerHub doesn't assign one.
+func auditLogId(log *dockerhub.AuditLog) string {
+	h := sha256.New()
+	for _, v := range []string{log.Account, log.Action, log.Name, log.Actor, log.Timestamp} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+
+	keys := make([]string, 0, len(log.Data))
+	for k := range log.Data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		h.Write([]byte(k + "=" + log.Data[k]))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,101 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestListEvents(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+
+	fixture := testFixture()
+	fixture.Organizations[0].AuditLogs = []dockerhubtest.AuditLog{
+		{Action: "org.member.add", Name: "acme", Actor: "alice", Data: map[string]string{"member": "bob", "role": "member"}, Timestamp: start},
+		{Action: "team.member.add", Name: "acme/ops", Actor: "alice", Data: map[string]string{"member": "bob"}, Timestamp: start.Add(time.Minute)},
+		{Action: "repo.team.permission.change", Name: "acme/web", Actor: "alice", Data: map[string]string{"team": "ops", "permission": "write", "previous_permission": "read"}, Timestamp: start.Add(2 * time.Minute)},
+		{Action: "org.member.remove", Name: "acme", Actor: "alice", Data: map[string]string{"member": "mallory", "role": "member"}, Timestamp: start.Add(3 * time.Minute)},
+		{Action: "repo.create", Name: "acme/api", Actor: "alice", Timestamp: start.Add(4 * time.Minute)},
+		{Action: "org.settings.change", Name: "acme", Actor: "alice", Timestamp: start.Add(5 * time.Minute)},
+		{Action: "org.member.role.change", Name: "acme", Actor: "alice", Data: map[string]string{"member": "carol", "role": "member", "previous_role": "editor"}, Timestamp: start.Add(6 * time.Minute)},
+	}
+	server := dockerhubtest.NewServer(t, fixture)
+	dh := newTestConnector(ctx, t, server)
+
+	listAll := func(cursor string) ([]*v2.Event, string) {
+		t.Helper()
+
+		var events []*v2.Event
+		for i := 0; ; i++ {
+			if i > 10 {
+				t.Fatal("event feed didn't catch up")
+			}
+
+			page, state, _, err := dh.ListEvents(ctx, nil, &pagination.StreamToken{Size: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events = append(events, page...)
+			cursor = state.Cursor
+			if !state.HasMore {
+				return events, cursor
+			}
+		}
+	}
+
+	events, cursor := listAll("")
+
+	bobId, carolId := server.UserID("bob"), server.UserID("carol")
+	opsId := strconv.Itoa(server.TeamID("acme", "ops"))
+
+	var got []string
+	for _, event := range events {
+		switch e := event.Event.(type) {
+		case *v2.Event_GrantEvent:
+			got = append(got, "grant "+e.GrantEvent.Grant.Entitlement.Id+" -> "+e.GrantEvent.Grant.Principal.Id.Resource)
+		case *v2.Event_RevokeEvent:
+			got = append(got, "revoke "+e.RevokeEvent.Entitlement.Id+" -> "+e.RevokeEvent.Principal.Id.Resource)
+		case *v2.Event_UsageEvent:
+			got = append(got, "usage "+e.UsageEvent.TargetResource.Id.Resource+" by "+e.UsageEvent.ActorResource.Id.Resource)
+		}
+	}
+
+	want := []string{
+		// changes revoke the previous role or permission first
+		"revoke org:acme:editor -> " + carolId,
+		"grant org:acme:member -> " + carolId,
+		"usage acme/api by " + server.UserID("alice"),
+		"revoke repository:acme/web:read -> " + opsId,
+		"grant repository:acme/web:write -> " + opsId,
+		"grant team:" + opsId + ":member -> " + bobId,
+		"grant org:acme:member -> " + bobId,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected event %q, got %q", want[i], got[i])
+		}
+	}
+
+	ids := map[string]bool{}
+	for _, event := range events {
+		if ids[event.Id] {
+			t.Errorf("expected distinct event ids, got %s twice", event.Id)
+		}
+		ids[event.Id] = true
+	}
+
+	events, _ = listAll(cursor)
+	if len(events) != 0 {
+		t.Errorf("expected no events after catching up, got %v", events)
+	}
+}
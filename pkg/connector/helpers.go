@@ -1,13 +1,20 @@
 package connector
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const ResourcesPageSize uint = 50
@@ -24,6 +31,30 @@ func annotationsForUserResourceType() annotations.Annotations {
 	return annos
 }
 
+// withSecretProfile sets the profile of a secret trait, which the SDK has no option for.
+func withSecretProfile(profile map[string]interface{}) rs.SecretTraitOption {
+	return func(t *v2.SecretTrait) error {
+		p, err := structpb.NewStruct(profile)
+		if err != nil {
+			return err
+		}
+
+		t.Profile = p
+
+		return nil
+	}
+}
+
+// annotationsWithRateLimit wraps the rate limit state reported by DockerHub, so that the syncer can pace itself.
+func annotationsWithRateLimit(rateLimitData *v2.RateLimitDescription) annotations.Annotations {
+	annos := annotations.Annotations{}
+	if rateLimitData != nil {
+		annos.WithRateLimiting(rateLimitData)
+	}
+
+	return annos
+}
+
 func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, string, error) {
 	b := &pagination.Bag{}
 	err := b.Unmarshal(i)
@@ -41,6 +72,104 @@ func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, strin
 	return b, b.PageToken(), nil
 }
 
+// entitlementSlug returns the last part of the entitlement ID, e.g. the role of organization entitlements.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	parts := strings.Split(entitlement.Id, ":")
+
+	return parts[len(parts)-1]
+}
+
+// userLogin returns the username of the user principal. Principals without a user profile,
+// e.g. taken from a grant, are looked up by their ID among the members of the organization.
+func userLogin(ctx context.Context, client *dockerhub.Client, principal *v2.Resource, orgSlug string) (string, error) {
+	if userTrait, err := rs.GetUserTrait(principal); err == nil {
+		if login, ok := rs.GetProfileStringValue(userTrait.Profile, "login"); ok && login != "" {
+			return login, nil
+		}
+	}
+
+	for member, err := range dockerhub.ListAll[dockerhub.User](ctx, client, 100, dockerhub.UsersEndpoint, orgSlug) {
+		if err != nil {
+			return "", fmt.Errorf("dockerhub-connector: failed to list members of organization %s: %w", orgSlug, err)
+		}
+
+		if member.Id == principal.Id.Resource {
+			return member.Username, nil
+		}
+	}
+
+	return "", status.Errorf(codes.NotFound, "dockerhub-connector: user %s is not a member of organization %s", principal.Id.Resource, orgSlug)
+}
+
+// inviteStatusInvited is the status DockerHub reports for an invitee who was sent an invitation.
+const inviteStatusInvited = "invited"
+
+// sentInvite returns the invitation DockerHub reports for the invitee. Invitations are accepted in bulk,
+// so the request succeeds even when DockerHub refuses to invite someone, the outcome is reported per invitee.
+func sentInvite(results []dockerhub.InviteeResult, invitee, orgSlug string) (*dockerhub.Invite, error) {
+	for _, result := range results {
+		if !strings.EqualFold(result.Invitee, invitee) {
+			continue
+		}
+
+		switch result.Status {
+		case inviteStatusInvited:
+			return result.Invite, nil
+		case "existing_org_member":
+			return nil, status.Errorf(codes.FailedPrecondition, "dockerhub-connector: %s is already a member of organization %s", invitee, orgSlug)
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "dockerhub-connector: DockerHub refused to invite %s to organization %s: %s", invitee, orgSlug, result.Status)
+		}
+	}
+
+	return nil, status.Errorf(codes.Unknown, "dockerhub-connector: DockerHub didn't report the invitation of %s to organization %s", invitee, orgSlug)
+}
+
+// pendingInvite returns the pending invitation of any of the Docker IDs or emails to the team. Without
+// a team, any invitation to the organization will do.
+func pendingInvite(invites []dockerhub.Invite, invitees []string, team string) *dockerhub.Invite {
+	for _, invite := range invites {
+		if team != "" && !strings.EqualFold(invite.Team, team) {
+			continue
+		}
+
+		for _, invitee := range invitees {
+			if strings.EqualFold(invite.Invitee, invitee) {
+				return &invite
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncedOrganizations returns the organizations configured to be synced, or all organizations
+// available to the credentials when there are none.
+func syncedOrganizations(ctx context.Context, client *dockerhub.Client, orgSlugs []string) ([]string, error) {
+	if len(orgSlugs) != 0 {
+		return orgSlugs, nil
+	}
+
+	var rv []string
+	paginationOpts := dockerhub.PaginationVars{Size: ResourcesPageSize}
+	for {
+		orgs, nextPage, _, err := client.ListOrganizations(ctx, &paginationOpts)
+		if err != nil {
+			return nil, fmt.Errorf("dockerhub-connector: failed to list organizations: %w", err)
+		}
+
+		for _, org := range orgs {
+			rv = append(rv, org.Name)
+		}
+
+		if nextPage == "" {
+			return rv, nil
+		}
+
+		paginationOpts.Page = nextPage
+	}
+}
+
 func splitFullName(fullName string) (string, string) {
 	parts := strings.Split(fullName, " ")
 
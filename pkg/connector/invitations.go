@@ -0,0 +1,128 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"golang.org/x/exp/slices"
+)
+
+type invitationResourceType struct {
+	resourceType *v2.ResourceType
+	client       *dockerhub.Client
+}
+
+func (i *invitationResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeInvitation
+}
+
+// Create a new connector resource for a pending DockerHub organization invitation.
+func invitationResource(ctx context.Context, invite *dockerhub.Invite, parentId *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"invitation_id": invite.Id,
+		"invitee":       invite.Invitee,
+		"inviter":       invite.Inviter,
+		"org":           invite.Org,
+		"role":          strings.ToLower(invite.Role),
+		"team":          invite.Team,
+		"created_at":    invite.CreatedAt,
+	}
+
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithDetailedStatus(v2.UserTrait_Status_STATUS_DISABLED, "invitation pending"),
+	}
+
+	// invitees are either a Docker ID or an email address
+	if strings.Contains(invite.Invitee, "@") {
+		userTraitOptions = append(userTraitOptions, rs.WithEmail(invite.Invitee, true))
+	} else {
+		userTraitOptions = append(userTraitOptions, rs.WithUserLogin(invite.Invitee))
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, invite.CreatedAt); err == nil {
+		userTraitOptions = append(userTraitOptions, rs.WithCreatedAt(createdAt))
+	}
+
+	resource, err := rs.NewUserResource(
+		invite.Invitee,
+		resourceTypeInvitation,
+		invite.Id,
+		userTraitOptions,
+		rs.WithParentResourceID(parentId),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns all the pending invitations of the organization as resource objects.
+func (i *invitationResourceType) List(ctx context.Context, parentId *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	invites, rateLimitData, err := i.client.ListInvites(ctx, parentId.Resource)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list invitations: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, invite := range invites {
+		inviteCopy := invite
+
+		ir, err := invitationResource(ctx, &inviteCopy, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, ir)
+	}
+
+	return rv, "", annos, nil
+}
+
+// Entitlements always returns an empty slice for invitations.
+func (i *invitationResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns the organization role the invitation was sent with, revoking it cancels the invitation.
+func (i *invitationResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	userTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	role, ok := rs.GetProfileStringValue(userTrait.Profile, "role")
+	if !ok || role == "" {
+		role = roleMember
+	}
+
+	if !slices.Contains(userRoles, role) {
+		return nil, "", nil, nil
+	}
+
+	or := &v2.Resource{Id: resource.ParentResourceId}
+
+	return []*v2.Grant{grant.NewGrant(or, role, resource)}, "", nil, nil
+}
+
+func invitationBuilder(client *dockerhub.Client) *invitationResourceType {
+	return &invitationResourceType{
+		resourceType: resourceTypeInvitation,
+		client:       client,
+	}
+}
@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const namespaceOwner = "owner"
+
+type namespaceResourceType struct {
+	resourceType *v2.ResourceType
+	client       *dockerhub.Client
+}
+
+func (n *namespaceResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeNamespace
+}
+
+// Create a new connector resource for the personal namespace of a DockerHub user.
+func namespaceResource(ctx context.Context, owner *dockerhub.User) (*v2.Resource, error) {
+	resource, err := rs.NewResource(
+		owner.Username,
+		resourceTypeNamespace,
+		owner.Username,
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeRepository.Id},
+		),
+		rs.WithDescription(fmt.Sprintf("Personal namespace of %s", owner.Username)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the personal namespace of the user the connector runs as. DockerHub doesn't list the
+// repositories of other users the account collaborates on, so their namespaces can't be synced.
+// Organization access tokens don't belong to a user, there is no personal namespace to sync then.
+func (n *namespaceResourceType) List(ctx context.Context, parentId *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId != nil || n.client.ScopedOrganization() != "" {
+		return nil, "", nil, nil
+	}
+
+	owner, rateLimitData, err := n.client.GetCurrentUser(ctx)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to get current user: %w", err)
+	}
+
+	nr, err := namespaceResource(ctx, owner)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Resource{nr}, "", annos, nil
+}
+
+// Entitlements returns the owner entitlement of the namespace, which has full access to all of its repositories.
+func (n *namespaceResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			namespaceOwner,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf("%s Namespace %s", resource.DisplayName, namespaceOwner)),
+			ent.WithDescription(fmt.Sprintf("Owner of the %s DockerHub namespace", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the grant of the owner entitlement to the user the namespace belongs to.
+func (n *namespaceResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	owner, rateLimitData, err := n.client.GetUser(ctx, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to get owner of namespace %s: %w", resource.Id.Resource, err)
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(resource, namespaceOwner, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: owner.Id}),
+	}, "", annos, nil
+}
+
+func namespaceBuilder(client *dockerhub.Client) *namespaceResourceType {
+	return &namespaceResourceType{
+		resourceType: resourceTypeNamespace,
+		client:       client,
+	}
+}
@@ -0,0 +1,36 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestNamespaceList(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+
+	namespaces, _, _, err := namespaceBuilder(newTestConnector(ctx, t, server).client).List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces) != 1 || namespaces[0].Id.Resource != "alice" {
+		t.Errorf("expected the personal namespace of alice, got %v", namespaces)
+	}
+
+	dh, err := New(ctx, "", &dockerhub.OrgAccessTokenAuth{Organization: "acme", Token: "dckr_oat_acme"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	namespaces, _, _, err = namespaceBuilder(dh.client).List(ctx, nil, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces) != 0 {
+		t.Errorf("expected no personal namespace for an organization access token, got %v", namespaces)
+	}
+}
@@ -0,0 +1,326 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	repoPullScope = "repo-pull"
+	repoPushScope = "repo-push"
+)
+
+type orgAccessTokenResourceType struct {
+	resourceType *v2.ResourceType
+	client       *dockerhub.Client
+	orgs         []string
+}
+
+func (o *orgAccessTokenResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeOrgAccessToken
+}
+
+// Create a new connector resource for an DockerHub organization access token.
+// The creator is only linked when they are still a member of the organization.
+func orgAccessTokenResource(ctx context.Context, token *dockerhub.OrgAccessToken, parentId *v2.ResourceId, createdById *v2.ResourceId) (*v2.Resource, error) {
+	var scopes, repositories []interface{}
+	for _, r := range token.Resources {
+		repositories = append(repositories, r.Path)
+		for _, scope := range r.Scopes {
+			if !slices.Contains(scopes, interface{}(scope)) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	profile := map[string]interface{}{
+		"token_id":     token.Id,
+		"label":        token.Label,
+		"description":  token.Description,
+		"created_by":   token.CreatedBy,
+		"is_active":    token.IsActive,
+		"scopes":       scopes,
+		"repositories": repositories,
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, token.CreatedAt); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedAt(createdAt))
+	}
+
+	if lastUsedAt, err := time.Parse(time.RFC3339, token.LastUsedAt); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretLastUsedAt(lastUsedAt))
+	}
+
+	if expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt); err == nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretExpiresAt(expiresAt))
+	}
+
+	if createdById != nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedByID(createdById))
+	}
+
+	resource, err := rs.NewSecretResource(
+		token.Label,
+		resourceTypeOrgAccessToken,
+		token.Id,
+		secretTraitOptions,
+		rs.WithParentResourceID(parentId),
+		rs.WithDescription(token.Description),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns all the access tokens of the organization as resource objects. Only organization owners
+// can see access tokens, so organizations where the credentials lack permission are skipped.
+func (o *orgAccessTokenResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: resourceTypeOrgAccessToken.Id})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	paginationOpts := dockerhub.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	orgSlug := parentId.Resource
+	tokens, nextPage, rateLimitData, err := o.client.ListOrgAccessTokens(ctx, orgSlug, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		if status.Code(err) == codes.PermissionDenied {
+			ctxzap.Extract(ctx).Warn("dockerhub-connector: not allowed to list access tokens of organization, skipping", zap.String("org", orgSlug))
+			return nil, "", annos, nil
+		}
+
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list organization access tokens: %w", err)
+	}
+
+	next, err := bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, next, annos, nil
+	}
+
+	memberIds, err := o.memberIds(ctx, orgSlug)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	var rv []*v2.Resource
+	for _, t := range tokens {
+		// the resources a token can access are only part of its details
+		token, rateLimitData, err := o.client.GetOrgAccessToken(ctx, orgSlug, t.Id)
+		if err != nil {
+			return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get organization access token %s: %w", t.Id, err)
+		}
+
+		var createdById *v2.ResourceId
+		if id, ok := memberIds[token.CreatedBy]; ok {
+			createdById = &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: id}
+		}
+
+		tr, err := orgAccessTokenResource(ctx, token, parentId, createdById)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, tr)
+		annos = annotationsWithRateLimit(rateLimitData)
+	}
+
+	return rv, next, annos, nil
+}
+
+// memberIds maps the usernames of the members of the organization to their IDs.
+func (o *orgAccessTokenResourceType) memberIds(ctx context.Context, orgSlug string) (map[string]string, error) {
+	rv := make(map[string]string)
+	for member, err := range dockerhub.ListAll[dockerhub.User](ctx, o.client, 100, dockerhub.UsersEndpoint, orgSlug) {
+		if err != nil {
+			return nil, fmt.Errorf("dockerhub-connector: failed to list members of organization %s: %w", orgSlug, err)
+		}
+
+		rv[member.Username] = member.Id
+	}
+
+	return rv, nil
+}
+
+// Entitlements always returns an empty slice for organization access tokens.
+func (o *orgAccessTokenResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns a grant of the repository permission matching the scopes of the token for each
+// repository of the organization it can access. Pushing implies pulling, so it maps to write.
+func (o *orgAccessTokenResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, fmt.Errorf("dockerhub-connector: organization access token %s has no parent organization", resource.Id.Resource)
+	}
+
+	orgSlug := resource.ParentResourceId.Resource
+	token, rateLimitData, err := o.client.GetOrgAccessToken(ctx, orgSlug, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to get organization access token %s: %w", resource.Id.Resource, err)
+	}
+
+	var rv []*v2.Grant
+	for _, r := range token.Resources {
+		namespace, repoSlug, ok := strings.Cut(r.Path, "/")
+		// wildcards cover repositories that may not exist yet
+		if !ok || namespace != orgSlug || strings.Contains(repoSlug, "*") {
+			continue
+		}
+
+		var permission string
+		switch {
+		case slices.Contains(r.Scopes, repoPushScope):
+			permission = readAndWritePermission
+		case slices.Contains(r.Scopes, repoPullScope):
+			permission = readPermission
+		default:
+			continue
+		}
+
+		rr := &v2.Resource{
+			Id:               &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: repositoryId(orgSlug, repoSlug)},
+			ParentResourceId: resource.ParentResourceId,
+		}
+
+		rv = append(rv, grant.NewGrant(rr, permission, resource))
+	}
+
+	return rv, "", annos, nil
+}
+
+// Rotate replaces the access token with a new one of the same label, description and resources, and deletes
+// the old one. DockerHub can't regenerate the secret of a token, so the replacement shows up as a new
+// resource on the next sync. Tokens with an expiration date keep their lifetime.
+func (o *orgAccessTokenResourceType) Rotate(ctx context.Context, resourceId *v2.ResourceId, credentialOptions *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	if credentialOptions != nil && credentialOptions.GetRandomPassword() == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: organization access tokens can only be rotated to a random secret")
+	}
+
+	orgSlug, old, err := o.findToken(ctx, resourceId.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if old == nil {
+		return nil, nil, status.Errorf(codes.NotFound, "dockerhub-connector: organization access token %s not found", resourceId.Resource)
+	}
+
+	req := dockerhub.CreateOrgAccessTokenReq{
+		Label:       old.Label,
+		Description: old.Description,
+		Resources:   old.Resources,
+	}
+
+	createdAt, createdErr := time.Parse(time.RFC3339, old.CreatedAt)
+	expiresAt, expiresErr := time.Parse(time.RFC3339, old.ExpiresAt)
+	if createdErr == nil && expiresErr == nil {
+		req.ExpiresAt = time.Now().Add(expiresAt.Sub(createdAt)).UTC().Format(time.RFC3339)
+	}
+
+	token, rateLimitData, err := o.client.CreateOrgAccessToken(ctx, orgSlug, req)
+	if err != nil {
+		return nil, annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to create replacement of organization access token %s: %w", old.Id, err)
+	}
+
+	rateLimitData, err = o.client.DeleteOrgAccessToken(ctx, orgSlug, old.Id)
+	if err != nil {
+		// the secret of the replacement would be lost, don't leave it behind
+		if _, rollbackErr := o.client.DeleteOrgAccessToken(ctx, orgSlug, token.Id); rollbackErr != nil {
+			ctxzap.Extract(ctx).Error(
+				"dockerhub-connector: failed to delete replacement of organization access token",
+				zap.String("org", orgSlug),
+				zap.String("token_id", token.Id),
+				zap.Error(rollbackErr),
+			)
+		}
+
+		return nil, annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to delete organization access token %s: %w", old.Id, err)
+	}
+
+	plaintexts := []*v2.PlaintextData{
+		{
+			Name:        "token",
+			Description: fmt.Sprintf("Access token of the %s organization, used together with the organization name to log in", orgSlug),
+			Bytes:       []byte(token.Token),
+		},
+	}
+
+	return plaintexts, annotationsWithRateLimit(rateLimitData), nil
+}
+
+// RotateCapabilityDetails tells that DockerHub generates the secret of organization access tokens.
+func (o *orgAccessTokenResourceType) RotateCapabilityDetails(_ context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+	}, nil, nil
+}
+
+// findToken returns the access token with the given ID and the organization it belongs to,
+// or a nil token when none of the synced organizations has it.
+func (o *orgAccessTokenResourceType) findToken(ctx context.Context, tokenId string) (string, *dockerhub.OrgAccessToken, error) {
+	orgSlugs, err := syncedOrganizations(ctx, o.client, o.orgs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, orgSlug := range orgSlugs {
+		token, _, err := o.client.GetOrgAccessToken(ctx, orgSlug, tokenId)
+		if err != nil {
+			if status.Code(err) == codes.NotFound || status.Code(err) == codes.PermissionDenied {
+				continue
+			}
+
+			return "", nil, fmt.Errorf("dockerhub-connector: failed to get organization access token %s of %s: %w", tokenId, orgSlug, err)
+		}
+
+		return orgSlug, token, nil
+	}
+
+	return "", nil, nil
+}
+
+func orgAccessTokenBuilder(client *dockerhub.Client, orgs []string) *orgAccessTokenResourceType {
+	return &orgAccessTokenResourceType{
+		resourceType: resourceTypeOrgAccessToken,
+		client:       client,
+		orgs:         orgs,
+	}
+}
@@ -0,0 +1,149 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func secretTrait(t *testing.T, resource *v2.Resource) *v2.SecretTrait {
+	t.Helper()
+
+	trait := &v2.SecretTrait{}
+	annos := annotations.Annotations(resource.Annotations)
+	if ok, err := annos.Pick(trait); err != nil || !ok {
+		t.Fatalf("expected %s to have a secret trait: %v", resource.Id.Resource, err)
+	}
+
+	return trait
+}
+
+func TestOrgAccessTokenList(t *testing.T) {
+	ctx := context.Background()
+	lastUsed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fixture := testFixture()
+	fixture.Organizations[0].AccessTokens = append(fixture.Organizations[0].AccessTokens, dockerhubtest.OrgAccessToken{
+		Token:      "dckr_oat_stale",
+		Label:      "stale",
+		CreatedBy:  "dave",
+		Inactive:   true,
+		LastUsedAt: lastUsed,
+	})
+	server := dockerhubtest.NewServer(t, fixture)
+	o := orgAccessTokenBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	resources, _, _, err := o.List(ctx, &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resources) != 2 {
+		t.Fatalf("expected 2 access tokens, got %d", len(resources))
+	}
+
+	ci := secretTrait(t, resources[0])
+	if ci.CreatedById.GetResource() != server.UserID("alice") {
+		t.Errorf("expected ci to be created by alice, got %v", ci.CreatedById)
+	}
+	if ci.LastUsedAt != nil {
+		t.Errorf("expected ci to be unused, got %v", ci.LastUsedAt)
+	}
+	if scopes := ci.Profile.Fields["scopes"].GetListValue().AsSlice(); len(scopes) != 2 {
+		t.Errorf("expected pull and push scopes, got %v", scopes)
+	}
+
+	stale := secretTrait(t, resources[1])
+	// dave isn't a member of acme anymore
+	if stale.CreatedById != nil {
+		t.Errorf("expected no creator, got %v", stale.CreatedById)
+	}
+	if !stale.LastUsedAt.AsTime().Equal(lastUsed) {
+		t.Errorf("expected stale to be last used at %v, got %v", lastUsed, stale.LastUsedAt.AsTime())
+	}
+	if stale.Profile.Fields["is_active"].GetBoolValue() {
+		t.Error("expected stale to be inactive")
+	}
+}
+
+func TestOrgAccessTokenListSkipsOrganizationsWithoutPermission(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	o := orgAccessTokenBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	// alice is only a member of globex
+	resources, _, _, err := o.List(ctx, &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "globex"}, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resources) != 0 {
+		t.Errorf("expected no access tokens, got %v", resources)
+	}
+}
+
+func TestOrgAccessTokenRotate(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	o := orgAccessTokenBuilder(newTestConnector(ctx, t, server).client, nil)
+	oldId := server.OrgAccessTokenID("acme", "ci")
+
+	plaintexts, _, err := o.Rotate(ctx, &v2.ResourceId{ResourceType: resourceTypeOrgAccessToken.Id, Resource: oldId}, &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := server.OrgAccessToken("acme", oldId); ok {
+		t.Error("expected the old token to be deleted")
+	}
+
+	newId := server.OrgAccessTokenID("acme", "ci")
+	token, ok := server.OrgAccessToken("acme", newId)
+	if !ok {
+		t.Fatal("expected a replacement token with the same label")
+	}
+	if len(plaintexts) != 1 || string(plaintexts[0].Bytes) != token.Token {
+		t.Errorf("expected the secret of the replacement to be returned, got %v", plaintexts)
+	}
+	if len(token.Repositories) != 2 || len(token.Repositories["acme/api"]) != 2 {
+		t.Errorf("expected the replacement to keep the repositories and scopes, got %v", token.Repositories)
+	}
+
+	dh, err := New(ctx, "", &dockerhub.OrgAccessTokenAuth{Organization: "acme", Token: token.Token}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dh.Validate(ctx); err != nil {
+		t.Errorf("expected the replacement to be usable: %v", err)
+	}
+}
+
+func TestOrgAccessTokenRotateKeepsOldTokenOnFailure(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	o := orgAccessTokenBuilder(newTestConnector(ctx, t, server).client, nil)
+	oldId := server.OrgAccessTokenID("acme", "ci")
+	acme := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}
+
+	server.Fail(dockerhubtest.Failure{Method: http.MethodDelete, Path: "/v2/orgs/acme/access-tokens/" + oldId, Status: http.StatusInternalServerError})
+
+	if _, _, err := o.Rotate(ctx, &v2.ResourceId{ResourceType: resourceTypeOrgAccessToken.Id, Resource: oldId}, nil); err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+
+	resources, _, _, err := o.List(ctx, acme, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0].Id.Resource != oldId {
+		t.Errorf("expected only the old token to be left, got %v", resources)
+	}
+}
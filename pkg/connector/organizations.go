@@ -12,7 +12,11 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -40,9 +44,10 @@ func orgResource(ctx context.Context, org *dockerhub.Organization) (*v2.Resource
 		resourceTypeOrg,
 		org.Name,
 		rs.WithAnnotation(
-			&v2.ChildResourceType{ResourceTypeId: resourceTypeUser.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeTeam.Id},
 			&v2.ChildResourceType{ResourceTypeId: resourceTypeRepository.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeInvitation.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeOrgAccessToken.Id},
 		),
 	)
 
@@ -65,9 +70,10 @@ func (o *orgResourceType) List(ctx context.Context, _ *v2.ResourceId, pToken *pa
 		Page: page,
 	}
 
-	orgs, nextPage, err := o.client.ListOrganizations(ctx, &paginationOpts)
+	orgs, nextPage, rateLimitData, err := o.client.ListOrganizations(ctx, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list organizations: %w", err)
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list organizations: %w", err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -94,7 +100,7 @@ func (o *orgResourceType) List(ctx context.Context, _ *v2.ResourceId, pToken *pa
 		rv = append(rv, resource)
 	}
 
-	return rv, next, nil, nil
+	return rv, next, annos, nil
 }
 
 // Entitlements returns a slice of entitlements for possible user roles under organization (owner, editor, member).
@@ -126,9 +132,10 @@ func (o *orgResourceType) Grants(ctx context.Context, resource *v2.Resource, pTo
 		Page: page,
 	}
 
-	users, nextPage, err := o.client.ListUsers(ctx, resource.Id.Resource, &paginationOpts)
+	users, nextPage, rateLimitData, err := o.client.ListUsers(ctx, resource.Id.Resource, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list users under organization %s: %w", resource.Id.Resource, err)
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list users under organization %s: %w", resource.Id.Resource, err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -145,16 +152,152 @@ func (o *orgResourceType) Grants(ctx context.Context, resource *v2.Resource, pTo
 			continue
 		}
 
-		userCopy := user
-		ur, err := userResource(ctx, &userCopy, resource.Id)
+		rv = append(rv, grant.NewGrant(resource, role, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: user.Id}))
+	}
+
+	return rv, next, annos, nil
+}
+
+// Grant sets the role of the user in the organization. Users who are not members yet are invited
+// with the role, they show up as members once they accept the invitation. A pending invitation with
+// the role already grants it.
+func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("dockerhub-connector: only users can be granted organization roles")
+	}
+
+	orgSlug, role := entitlement.Resource.Id.Resource, entitlementSlug(entitlement)
+	if !slices.Contains(userRoles, role) {
+		return nil, fmt.Errorf("dockerhub-connector: invalid organization role %s", role)
+	}
+
+	username, err := userLogin(ctx, o.client, principal, orgSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	member, rateLimitData, err := o.client.GetOrganizationMember(ctx, orgSlug, username)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get member %s of organization %s: %w", username, orgSlug, err)
+	}
+
+	if member == nil {
+		invites, rateLimitData, err := o.client.ListInvites(ctx, orgSlug)
 		if err != nil {
-			return nil, "", nil, err
+			return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to list invitations of organization %s: %w", orgSlug, err)
+		}
+
+		// a retried grant finds the invitation it sent before
+		if invite := pendingInvite(invites, []string{username}, ""); invite != nil {
+			if !strings.EqualFold(invite.Role, role) {
+				return annotationsWithRateLimit(rateLimitData), status.Errorf(codes.FailedPrecondition,
+					"dockerhub-connector: %s has a pending invitation to organization %s as %s, revoke it to invite them as %s", username, orgSlug, strings.ToLower(invite.Role), role)
+			}
+
+			annos := annotationsWithRateLimit(rateLimitData)
+			annos.Append(&v2.GrantAlreadyExists{})
+
+			return annos, nil
+		}
+
+		results, rateLimitData, err := o.client.InviteToOrganization(ctx, orgSlug, "", role, username)
+		if err != nil {
+			return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to invite %s to organization %s: %w", username, orgSlug, err)
+		}
+
+		if _, err := sentInvite(results, username, orgSlug); err != nil {
+			return annotationsWithRateLimit(rateLimitData), err
+		}
+
+		l.Info("dockerhub-connector: invited user to organization", zap.String("user", username), zap.String("org", orgSlug), zap.String("role", role))
+
+		return annotationsWithRateLimit(rateLimitData), nil
+	}
+
+	if strings.EqualFold(member.Role, role) {
+		annos := annotationsWithRateLimit(rateLimitData)
+		annos.Append(&v2.GrantAlreadyExists{})
+
+		return annos, nil
+	}
+
+	rateLimitData, err = o.client.UpdateOrganizationMemberRole(ctx, orgSlug, username, role)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to set role of %s in organization %s: %w", username, orgSlug, err)
+	}
+
+	return annotationsWithRateLimit(rateLimitData), nil
+}
+
+// Revoke takes the role away from the user. Owners and editors are downgraded to members,
+// revoking the member role removes the user from the organization.
+// For pending invitations, revoking the role they were sent with cancels the invitation.
+func (o *orgResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	principal, entitlement := grant.Principal, grant.Entitlement
+	if principal.Id.ResourceType == resourceTypeInvitation.Id {
+		return o.cancelInvitation(ctx, principal.Id.Resource)
+	}
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("dockerhub-connector: only users can be revoked organization roles")
+	}
+
+	orgSlug, role := entitlement.Resource.Id.Resource, entitlementSlug(entitlement)
+
+	username, err := userLogin(ctx, o.client, principal, orgSlug)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+
+		return nil, err
+	}
+
+	member, rateLimitData, err := o.client.GetOrganizationMember(ctx, orgSlug, username)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get member %s of organization %s: %w", username, orgSlug, err)
+	}
+
+	if member == nil || !strings.EqualFold(member.Role, role) {
+		annos := annotationsWithRateLimit(rateLimitData)
+		annos.Append(&v2.GrantAlreadyRevoked{})
+
+		return annos, nil
+	}
+
+	if role == roleMember {
+		rateLimitData, err = o.client.RemoveOrganizationMember(ctx, orgSlug, username)
+		if err != nil {
+			return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to remove %s from organization %s: %w", username, orgSlug, err)
+		}
+
+		return annotationsWithRateLimit(rateLimitData), nil
+	}
+
+	rateLimitData, err = o.client.UpdateOrganizationMemberRole(ctx, orgSlug, username, roleMember)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to downgrade %s to member of organization %s: %w", username, orgSlug, err)
+	}
+
+	return annotationsWithRateLimit(rateLimitData), nil
+}
+
+func (o *orgResourceType) cancelInvitation(ctx context.Context, inviteId string) (annotations.Annotations, error) {
+	rateLimitData, err := o.client.DeleteInvite(ctx, inviteId)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		// accepted or already canceled
+		if status.Code(err) == codes.NotFound {
+			annos.Append(&v2.GrantAlreadyRevoked{})
+			return annos, nil
 		}
 
-		rv = append(rv, grant.NewGrant(resource, role, ur.Id))
+		return annos, fmt.Errorf("dockerhub-connector: failed to cancel invitation %s: %w", inviteId, err)
 	}
 
-	return rv, next, nil, nil
+	return annos, nil
 }
 
 func orgBuilder(client *dockerhub.Client, orgs []string) *orgResourceType {
@@ -0,0 +1,232 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestConnector creates a connector authenticated as alice, an owner of acme.
+func newTestConnector(ctx context.Context, t *testing.T, server *dockerhubtest.Server) *DockerHub {
+	t.Helper()
+
+	dh, err := New(ctx, "alice", &dockerhub.PasswordAuth{Username: "alice", Password: "wonderland"}, nil, dockerhub.WithBaseURL(server.BaseURL()))
+	if err != nil {
+		t.Fatalf("failed to create connector: %v", err)
+	}
+
+	return dh
+}
+
+func testUserResource(ctx context.Context, t *testing.T, server *dockerhubtest.Server, username string) *v2.Resource {
+	t.Helper()
+
+	ur, err := userResource(ctx, &dockerhub.User{BaseResource: dockerhub.BaseResource{Id: server.UserID(username)}, Username: username})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ur
+}
+
+func testOrgEntitlement(ctx context.Context, t *testing.T, orgSlug, role string) *v2.Entitlement {
+	t.Helper()
+
+	or, err := orgResource(ctx, &dockerhub.Organization{Name: orgSlug})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entitlements, _, _, err := orgBuilder(nil, nil).Entitlements(ctx, or, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entitlements {
+		if entitlementSlug(e) == role {
+			return e
+		}
+	}
+
+	t.Fatalf("no %s entitlement", role)
+	return nil
+}
+
+func assertExistsAnnotation(t *testing.T, annos annotations.Annotations, want bool) {
+	t.Helper()
+
+	if got := annos.Contains(&v2.GrantAlreadyExists{}); got != want {
+		t.Errorf("expected GrantAlreadyExists annotation to be %v, got %v", want, got)
+	}
+}
+
+func assertRevokedAnnotation(t *testing.T, annos annotations.Annotations, want bool) {
+	t.Helper()
+
+	if got := annos.Contains(&v2.GrantAlreadyRevoked{}); got != want {
+		t.Errorf("expected GrantAlreadyRevoked annotation to be %v, got %v", want, got)
+	}
+}
+
+func TestOrgGrant(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Users = append(fixture.Users, dockerhubtest.User{Username: "dave", Email: "dave@example.com"})
+	server := dockerhubtest.NewServer(t, fixture)
+	o := orgBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	t.Run("changes role of member", func(t *testing.T) {
+		annos, err := o.Grant(ctx, testUserResource(ctx, t, server, "bob"), testOrgEntitlement(ctx, t, "acme", roleEditor))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertExistsAnnotation(t, annos, false)
+		if role := server.Members("acme")["bob"]; role != roleEditor {
+			t.Errorf("expected bob to be an editor, got %q", role)
+		}
+	})
+
+	t.Run("already granted", func(t *testing.T) {
+		annos, err := o.Grant(ctx, testUserResource(ctx, t, server, "alice"), testOrgEntitlement(ctx, t, "acme", roleOwner))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertExistsAnnotation(t, annos, true)
+	})
+
+	t.Run("invites non member", func(t *testing.T) {
+		_, err := o.Grant(ctx, testUserResource(ctx, t, server, "dave"), testOrgEntitlement(ctx, t, "acme", roleMember))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invites := server.Invites("acme")
+		if len(invites) != 1 || invites[0].Invitee != "dave" || invites[0].Role != roleMember {
+			t.Errorf("expected dave to be invited as member, got %v", invites)
+		}
+		if _, ok := server.Members("acme")["dave"]; ok {
+			t.Error("expected dave not to be a member before accepting the invite")
+		}
+	})
+
+	t.Run("pending invitation", func(t *testing.T) {
+		annos, err := o.Grant(ctx, testUserResource(ctx, t, server, "dave"), testOrgEntitlement(ctx, t, "acme", roleMember))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertExistsAnnotation(t, annos, true)
+		if invites := server.Invites("acme"); len(invites) != 1 {
+			t.Errorf("expected no second invitation, got %v", invites)
+		}
+
+		_, err = o.Grant(ctx, testUserResource(ctx, t, server, "dave"), testOrgEntitlement(ctx, t, "acme", roleEditor))
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected granting another role to a pending invitation to fail, got %v", err)
+		}
+	})
+
+	t.Run("refused invitation", func(t *testing.T) {
+		principal, err := userResource(ctx, &dockerhub.User{BaseResource: dockerhub.BaseResource{Id: "404"}, Username: "nobody"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = o.Grant(ctx, principal, testOrgEntitlement(ctx, t, "acme", roleMember))
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected inviting an unknown Docker ID to fail, got %v", err)
+		}
+	})
+
+	t.Run("rejects non users", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: "1"}}
+		if _, err := o.Grant(ctx, principal, testOrgEntitlement(ctx, t, "acme", roleMember)); err == nil {
+			t.Error("expected granting a role to a team to fail")
+		}
+	})
+}
+
+func TestOrgRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	o := orgBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	revoke := func(t *testing.T, principal *v2.Resource, role string) annotations.Annotations {
+		t.Helper()
+
+		e := testOrgEntitlement(ctx, t, "acme", role)
+		annos, err := o.Revoke(ctx, grant.NewGrant(e.Resource, role, principal))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return annos
+	}
+
+	t.Run("downgrades editor to member", func(t *testing.T) {
+		assertRevokedAnnotation(t, revoke(t, testUserResource(ctx, t, server, "carol"), roleEditor), false)
+
+		if role := server.Members("acme")["carol"]; role != roleMember {
+			t.Errorf("expected carol to be a member, got %q", role)
+		}
+	})
+
+	t.Run("role not held", func(t *testing.T) {
+		assertRevokedAnnotation(t, revoke(t, testUserResource(ctx, t, server, "bob"), roleOwner), true)
+
+		if role := server.Members("acme")["bob"]; role != roleMember {
+			t.Errorf("expected bob to stay a member, got %q", role)
+		}
+	})
+
+	t.Run("removes member", func(t *testing.T) {
+		// grants synced before the principal profile was included only carry its ID
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: server.UserID("bob")}}
+
+		assertRevokedAnnotation(t, revoke(t, principal, roleMember), false)
+
+		if _, ok := server.Members("acme")["bob"]; ok {
+			t.Error("expected bob to be removed from acme")
+		}
+
+		assertRevokedAnnotation(t, revoke(t, principal, roleMember), true)
+	})
+}
+
+func TestOrgRevokeCancelsInvitation(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Organizations[0].Invites = []dockerhubtest.Invite{{Invitee: "erin@example.com", Role: roleEditor}}
+	server := dockerhubtest.NewServer(t, fixture)
+	o := orgBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	e := testOrgEntitlement(ctx, t, "acme", roleEditor)
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeInvitation.Id, Resource: server.InviteID("acme", "erin@example.com")}}
+	g := grant.NewGrant(e.Resource, roleEditor, principal)
+
+	annos, err := o.Revoke(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevokedAnnotation(t, annos, false)
+	if invites := server.Invites("acme"); len(invites) != 0 {
+		t.Errorf("expected the invitation to be canceled, got %v", invites)
+	}
+
+	annos, err = o.Revoke(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevokedAnnotation(t, annos, true)
+}
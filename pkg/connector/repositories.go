@@ -3,6 +3,8 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -11,6 +13,9 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -21,21 +26,35 @@ const (
 
 var repoPermissions = []string{readPermission, readAndWritePermission, adminPermission}
 
+// The description of a repository to create can start with one of these tags to set its visibility,
+// e.g. "[public] The website". The tag is not part of the description saved in DockerHub.
+const (
+	privateRepositoryTag = "[private]"
+	publicRepositoryTag  = "[public]"
+)
+
 type repositoryResourceType struct {
 	resourceType *v2.ResourceType
 	client       *dockerhub.Client
+	orgs         []string
 }
 
 func (r *repositoryResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
 	return resourceTypeRepository
 }
 
-// Create a new connector resource for an DockerHub repository.
+// Create a new connector resource for an DockerHub repository. Repository names are only unique within
+// their namespace, so the resource ID is qualified with it, e.g. acme/api.
 func repositoryResource(ctx context.Context, repository *dockerhub.Repository, parentId *v2.ResourceId) (*v2.Resource, error) {
+	namespace := repository.NameSpace
+	if namespace == "" {
+		namespace = parentId.GetResource()
+	}
+
 	resource, err := rs.NewResource(
 		titleCase(repository.Name),
 		resourceTypeRepository,
-		repository.Name,
+		repositoryId(namespace, repository.Name),
 		rs.WithParentResourceID(parentId),
 		rs.WithDescription(repository.Description),
 	)
@@ -63,9 +82,10 @@ func (r *repositoryResourceType) List(ctx context.Context, parentId *v2.Resource
 		Page: page,
 	}
 
-	repositories, nextPage, err := r.client.ListRepositories(ctx, parentId.Resource, &paginationOpts)
+	repositories, nextPage, rateLimitData, err := r.client.ListRepositories(ctx, parentId.Resource, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list repositories: %w", err)
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list repositories: %w", err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -85,7 +105,7 @@ func (r *repositoryResourceType) List(ctx context.Context, parentId *v2.Resource
 		rv = append(rv, rr)
 	}
 
-	return rv, next, nil, nil
+	return rv, next, annos, nil
 }
 
 // Entitlements returns a slice of entitlements for possible permissions of repositories (read, read & write, admin).
@@ -94,7 +114,7 @@ func (r *repositoryResourceType) Entitlements(_ context.Context, resource *v2.Re
 
 	for _, p := range repoPermissions {
 		permissionOptions := []ent.EntitlementOption{
-			ent.WithGrantableTo(resourceTypeTeam),
+			ent.WithGrantableTo(resourceTypeTeam, resourceTypeOrgAccessToken, resourceTypeUser),
 			ent.WithDisplayName(fmt.Sprintf("%s Repository %s", resource.DisplayName, p)),
 			ent.WithDescription(fmt.Sprintf("%s access to %s repository in DockerHub", titleCase(p), resource.DisplayName)),
 		}
@@ -105,9 +125,18 @@ func (r *repositoryResourceType) Entitlements(_ context.Context, resource *v2.Re
 	return rv, "", nil, nil
 }
 
-// Grants returns a slice of grants for each team permission set in repositories.
+// Grants returns a slice of grants for each team permission set in repositories of organizations,
+// and for each collaborator of repositories in personal namespaces.
 func (r *repositoryResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	repoId, orgSlug := resource.Id.Resource, resource.ParentResourceId.Resource
+	orgSlug, repoId, err := parseRepositoryId(resource.Id, resource.ParentResourceId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if resource.ParentResourceId.GetResourceType() == resourceTypeNamespace.Id {
+		return r.collaboratorGrants(ctx, resource, orgSlug, repoId, pToken)
+	}
+
 	bag, page, err := parsePageToken(pToken.Token, resource.Id)
 	if err != nil {
 		return nil, "", nil, err
@@ -118,9 +147,9 @@ func (r *repositoryResourceType) Grants(ctx context.Context, resource *v2.Resour
 		Page: page,
 	}
 
-	perms, nextPage, err := r.client.ListRepositoryPermissions(ctx, orgSlug, repoId, &paginationOpts)
+	perms, nextPage, rateLimitData, err := r.client.ListRepositoryPermissions(ctx, orgSlug, repoId, &paginationOpts)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list repository permissions: %w", err)
+		return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to list repository permissions: %w", err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -130,10 +159,10 @@ func (r *repositoryResourceType) Grants(ctx context.Context, resource *v2.Resour
 
 	var rv []*v2.Grant
 	for _, perm := range perms {
-		// fetch team Id from obtained team name
-		team, err := r.client.GetTeam(ctx, orgSlug, perm.TeamName)
+		// permissions only name the team, the client resolves them from the teams of the organization it lists once
+		teamId, teamRateLimitData, err := r.client.ResolveTeamID(ctx, orgSlug, perm.TeamName)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to get team: %w", err)
+			return nil, "", annotationsWithRateLimit(teamRateLimitData), fmt.Errorf("dockerhub-connector: failed to get team: %w", err)
 		}
 
 		g := grant.NewGrant(
@@ -141,24 +170,304 @@ func (r *repositoryResourceType) Grants(ctx context.Context, resource *v2.Resour
 			perm.Permission,
 			&v2.ResourceId{
 				ResourceType: resourceTypeTeam.Id,
-				Resource:     fmt.Sprintf("%d", team.Id),
+				Resource:     fmt.Sprintf("%d", teamId),
 			},
 			grant.WithAnnotation(
 				&v2.GrantExpandable{
-					EntitlementIds: []string{fmt.Sprintf("team:%d:%s", team.Id, teamMembership)},
+					EntitlementIds: []string{fmt.Sprintf("team:%d:%s", teamId, teamMembership)},
 				},
 			),
 		)
 
 		rv = append(rv, g)
+		if teamRateLimitData != nil {
+			rateLimitData = teamRateLimitData
+		}
+	}
+
+	return rv, next, annotationsWithRateLimit(rateLimitData), nil
+}
+
+// collaboratorGrants returns a grant of their permission on the repository to each collaborator. DockerHub
+// used to give every collaborator read and write access, which is assumed when no permission is reported.
+func (r *repositoryResourceType) collaboratorGrants(ctx context.Context, resource *v2.Resource, namespace, repoSlug string, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	bag, page, err := parsePageToken(pToken.Token, resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	paginationOpts := dockerhub.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	collaborators, nextPage, rateLimitData, err := r.client.ListRepositoryCollaborators(ctx, namespace, repoSlug, &paginationOpts)
+	if err != nil {
+		return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to list collaborators of repository %s/%s: %w", namespace, repoSlug, err)
+	}
+
+	next, err := bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for _, collaborator := range collaborators {
+		permission := strings.ToLower(collaborator.Permission)
+		if permission == "" {
+			permission = readAndWritePermission
+		}
+
+		if !slices.Contains(repoPermissions, permission) {
+			continue
+		}
+
+		user, userRateLimitData, err := r.client.LookupUser(ctx, collaborator.Username)
+		if err != nil {
+			// the account was deleted since it was added as collaborator
+			if status.Code(err) == codes.NotFound {
+				continue
+			}
+
+			return nil, "", annotationsWithRateLimit(userRateLimitData), fmt.Errorf("dockerhub-connector: failed to get user %s: %w", collaborator.Username, err)
+		}
+
+		rv = append(rv, grant.NewGrant(resource, permission, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: user.Id}))
+		if userRateLimitData != nil {
+			rateLimitData = userRateLimitData
+		}
+	}
+
+	return rv, next, annotationsWithRateLimit(rateLimitData), nil
+}
+
+// Grant gives the team the permission on the repository. A team can only hold one permission
+// per repository, so an existing permission is replaced, whether it's higher or lower.
+func (r *repositoryResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	orgSlug, repoSlug, teamId, permission, err := repositoryPermissionTarget(principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	current, rateLimitData, err := r.client.GetRepositoryPermission(ctx, orgSlug, repoSlug, teamId)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get permission of team %d on repository %s: %w", teamId, repoSlug, err)
+	}
+
+	switch {
+	case current == nil:
+		rateLimitData, err = r.client.AddRepositoryPermission(ctx, orgSlug, repoSlug, teamId, permission)
+	case current.Permission == permission:
+		annos := annotationsWithRateLimit(rateLimitData)
+		annos.Append(&v2.GrantAlreadyExists{})
+
+		return annos, nil
+	default:
+		rateLimitData, err = r.client.UpdateRepositoryPermission(ctx, orgSlug, repoSlug, teamId, permission)
+	}
+
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to grant %s permission on repository %s to team %d: %w", permission, repoSlug, teamId, err)
+	}
+
+	return annotationsWithRateLimit(rateLimitData), nil
+}
+
+// Revoke takes away the access of the team to the repository, unless the team holds a different
+// permission than the revoked one by now.
+func (r *repositoryResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	orgSlug, repoSlug, teamId, permission, err := repositoryPermissionTarget(grant.Principal, grant.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	current, rateLimitData, err := r.client.GetRepositoryPermission(ctx, orgSlug, repoSlug, teamId)
+	if err != nil {
+		return annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get permission of team %d on repository %s: %w", teamId, repoSlug, err)
+	}
+
+	if current == nil || current.Permission != permission {
+		annos := annotationsWithRateLimit(rateLimitData)
+		annos.Append(&v2.GrantAlreadyRevoked{})
+
+		return annos, nil
+	}
+
+	rateLimitData, err = r.client.RemoveRepositoryPermission(ctx, orgSlug, repoSlug, teamId)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			annos.Append(&v2.GrantAlreadyRevoked{})
+			return annos, nil
+		}
+
+		return annos, fmt.Errorf("dockerhub-connector: failed to revoke %s permission on repository %s from team %d: %w", permission, repoSlug, teamId, err)
+	}
+
+	return annos, nil
+}
+
+// Create creates a repository in the parent organization. Repositories are private, unless the description
+// starts with the [public] tag.
+func (r *repositoryResourceType) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != resourceTypeOrg.Id {
+		return nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: repositories can only be created in an organization")
+	}
+
+	orgSlug := resource.ParentResourceId.Resource
+
+	// synced repositories are displayed in title case, while DockerHub only allows lowercase names
+	name := resource.GetId().GetResource()
+	if namespace, repoSlug, ok := strings.Cut(name, "/"); ok {
+		if namespace != orgSlug {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "dockerhub-connector: repository %s doesn't belong to organization %s", name, orgSlug)
+		}
+		name = repoSlug
+	}
+	if name == "" {
+		name = strings.ToLower(resource.DisplayName)
+	}
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: repository name is required")
+	}
+
+	description, private := repositoryVisibility(resource.Description)
+
+	repository, rateLimitData, err := r.client.CreateRepository(ctx, orgSlug, name, description, private)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("dockerhub-connector: failed to create repository %s in %s: %w", name, orgSlug, err)
+	}
+
+	rr, err := repositoryResource(ctx, repository, resource.ParentResourceId)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return rr, annos, nil
+}
+
+// repositoryVisibility splits the visibility tag off the description of a repository to create,
+// returning the description and whether the repository is private, which it is by default.
+func repositoryVisibility(description string) (string, bool) {
+	trimmed := strings.TrimSpace(description)
+
+	if rest, ok := strings.CutPrefix(trimmed, publicRepositoryTag); ok {
+		return strings.TrimSpace(rest), false
+	}
+	if rest, ok := strings.CutPrefix(trimmed, privateRepositoryTag); ok {
+		return strings.TrimSpace(rest), true
+	}
+
+	return description, true
+}
+
+// Delete deletes the repository. IDs synced before they were qualified with the namespace don't tell which
+// organization the repository belongs to, so it is looked up in the synced organizations first, refusing
+// to guess when several of them have it.
+func (r *repositoryResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if orgSlug, repoSlug, ok := strings.Cut(resourceId.Resource, "/"); ok {
+		return r.deleteRepository(ctx, orgSlug, repoSlug)
+	}
+
+	repoSlug := resourceId.Resource
+	orgSlugs, err := syncedOrganizations(ctx, r.client, r.orgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []string
+	for _, orgSlug := range orgSlugs {
+		_, _, err := r.client.GetRepository(ctx, orgSlug, repoSlug)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				continue
+			}
+
+			return nil, fmt.Errorf("dockerhub-connector: failed to get repository %s/%s: %w", orgSlug, repoSlug, err)
+		}
+
+		owners = append(owners, orgSlug)
+	}
+
+	switch len(owners) {
+	case 0:
+		return nil, status.Errorf(codes.NotFound, "dockerhub-connector: repository %s not found", repoSlug)
+	case 1:
+	default:
+		return nil, status.Errorf(codes.FailedPrecondition, "dockerhub-connector: repository %s exists in several organizations: %s", repoSlug, strings.Join(owners, ", "))
+	}
+
+	return r.deleteRepository(ctx, owners[0], repoSlug)
+}
+
+func (r *repositoryResourceType) deleteRepository(ctx context.Context, orgSlug, repoSlug string) (annotations.Annotations, error) {
+	rateLimitData, err := r.client.DeleteRepository(ctx, orgSlug, repoSlug)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return annos, fmt.Errorf("dockerhub-connector: failed to delete repository %s/%s: %w", orgSlug, repoSlug, err)
+	}
+
+	return annos, nil
+}
+
+// repositoryId returns the resource ID of the repository in the namespace, e.g. acme/api.
+func repositoryId(namespace, repoSlug string) string {
+	return namespace + "/" + repoSlug
+}
+
+// parseRepositoryId returns the namespace and name of the repository with the given resource ID. IDs synced
+// by earlier versions only hold the name of the repository, the namespace is taken from the parent then.
+func parseRepositoryId(resourceId *v2.ResourceId, parentId *v2.ResourceId) (string, string, error) {
+	if namespace, repoSlug, ok := strings.Cut(resourceId.GetResource(), "/"); ok {
+		if namespace == "" || repoSlug == "" {
+			return "", "", fmt.Errorf("dockerhub-connector: invalid repository id %s", resourceId.GetResource())
+		}
+
+		return namespace, repoSlug, nil
+	}
+
+	if parentId == nil {
+		return "", "", fmt.Errorf("dockerhub-connector: repository %s has no parent organization", resourceId.GetResource())
+	}
+
+	return parentId.Resource, resourceId.GetResource(), nil
+}
+
+// repositoryPermissionTarget returns the organization, repository, team ID and permission
+// addressed by a repository entitlement and a team principal. Access of organization access tokens and
+// collaborators is synced, but only teams can be provisioned.
+func repositoryPermissionTarget(principal *v2.Resource, entitlement *v2.Entitlement) (string, string, int, string, error) {
+	if principal.Id.ResourceType != resourceTypeTeam.Id {
+		return "", "", 0, "", status.Errorf(
+			codes.InvalidArgument,
+			"dockerhub-connector: repository permissions can only be granted to and revoked from teams, not %s principals",
+			principal.Id.ResourceType,
+		)
+	}
+
+	teamId, err := strconv.Atoi(principal.Id.Resource)
+	if err != nil {
+		return "", "", 0, "", fmt.Errorf("dockerhub-connector: invalid team id %s: %w", principal.Id.Resource, err)
+	}
+
+	orgSlug, repoSlug, err := parseRepositoryId(entitlement.Resource.Id, entitlement.Resource.ParentResourceId)
+	if err != nil {
+		return "", "", 0, "", err
+	}
+
+	permission := entitlementSlug(entitlement)
+	if !slices.Contains(repoPermissions, permission) {
+		return "", "", 0, "", fmt.Errorf("dockerhub-connector: invalid repository permission %s", permission)
 	}
 
-	return rv, next, nil, nil
+	return orgSlug, repoSlug, teamId, permission, nil
 }
 
-func repositoryBuilder(client *dockerhub.Client) *repositoryResourceType {
+func repositoryBuilder(client *dockerhub.Client, orgs []string) *repositoryResourceType {
 	return &repositoryResourceType{
 		resourceType: resourceTypeRepository,
 		client:       client,
+		orgs:         orgs,
 	}
 }
@@ -0,0 +1,456 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testRepositoryEntitlement(ctx context.Context, t *testing.T, orgSlug, repoSlug, permission string) *v2.Entitlement {
+	t.Helper()
+
+	rr, err := repositoryResource(ctx, &dockerhub.Repository{Name: repoSlug, NameSpace: orgSlug}, &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgSlug})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entitlements, _, _, err := repositoryBuilder(nil, nil).Entitlements(ctx, rr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entitlements {
+		if entitlementSlug(e) == permission {
+			return e
+		}
+	}
+
+	t.Fatalf("no %s entitlement", permission)
+	return nil
+}
+
+func testTeamPrincipal(server *dockerhubtest.Server, orgSlug, teamName string) *v2.Resource {
+	return &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: fmt.Sprintf("%d", server.TeamID(orgSlug, teamName))}}
+}
+
+func TestRepositoryGrant(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	tests := []struct {
+		name       string
+		repo       string
+		team       string
+		permission string
+		exists     bool
+	}{
+		{name: "adds access", repo: "web", team: "ops", permission: readPermission},
+		{name: "upgrades read to admin", repo: "web", team: "developers", permission: adminPermission},
+		{name: "downgrades admin to read", repo: "api", team: "ops", permission: readPermission},
+		{name: "already granted", repo: "api", team: "developers", permission: readAndWritePermission, exists: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			annos, err := r.Grant(ctx, testTeamPrincipal(server, "acme", tc.team), testRepositoryEntitlement(ctx, t, "acme", tc.repo, tc.permission))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertExistsAnnotation(t, annos, tc.exists)
+			if got := server.RepositoryPermissions("acme", tc.repo)[tc.team]; got != tc.permission {
+				t.Errorf("expected %s to have %s permission on %s, got %q", tc.team, tc.permission, tc.repo, got)
+			}
+		})
+	}
+}
+
+func TestRepositoryRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	revoke := func(t *testing.T, team, permission string) bool {
+		t.Helper()
+
+		e := testRepositoryEntitlement(ctx, t, "acme", "api", permission)
+		annos, err := r.Revoke(ctx, grant.NewGrant(e.Resource, permission, testTeamPrincipal(server, "acme", team)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return annos.Contains(&v2.GrantAlreadyRevoked{})
+	}
+
+	// ops holds admin, not read
+	if !revoke(t, "ops", readPermission) {
+		t.Error("expected revoking a permission the team doesn't hold to be a no-op")
+	}
+	if got := server.RepositoryPermissions("acme", "api")["ops"]; got != adminPermission {
+		t.Errorf("expected ops to keep admin permission, got %q", got)
+	}
+
+	if revoke(t, "ops", adminPermission) {
+		t.Error("unexpected GrantAlreadyRevoked annotation")
+	}
+	if _, ok := server.RepositoryPermissions("acme", "api")["ops"]; ok {
+		t.Error("expected ops to lose access to api")
+	}
+
+	if !revoke(t, "ops", adminPermission) {
+		t.Error("expected revoking twice to be a no-op")
+	}
+}
+
+func TestRepositoryGrantsResolveTeamsOnce(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	for _, repo := range []string{"api", "web"} {
+		e := testRepositoryEntitlement(ctx, t, "acme", repo, readPermission)
+		grants, _, _, err := r.Grants(ctx, e.Resource, &pagination.Token{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(grants) == 0 {
+			t.Errorf("expected team grants on %s", repo)
+		}
+	}
+
+	var teamLists, teamLookups int
+	for _, req := range server.Requests() {
+		switch {
+		case req == "GET /v2/orgs/acme/groups":
+			teamLists++
+		case strings.HasPrefix(req, "GET /v2/orgs/acme/groups/"):
+			teamLookups++
+		}
+	}
+
+	if teamLists != 1 || teamLookups != 0 {
+		t.Errorf("expected the teams to be listed once and not looked up one by one, got %d lists and %d lookups", teamLists, teamLookups)
+	}
+}
+
+func TestRepositoryGrantsResolveTeamsCreatedSince(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+	e := testRepositoryEntitlement(ctx, t, "acme", "web", readPermission)
+
+	// fill the team cache
+	if _, _, _, err := r.Grants(ctx, e.Resource, &pagination.Token{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// another client creates a team and gives it access, which the cache doesn't know about
+	other := newTestConnector(ctx, t, server).client
+	team, _, err := other.CreateTeam(ctx, "acme", "qa", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.AddRepositoryPermission(ctx, "acme", "web", team.Id, readPermission); err != nil {
+		t.Fatal(err)
+	}
+
+	grants, _, _, err := r.Grants(ctx, e.Resource, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, g := range grants {
+		found = found || g.Principal.Id.Resource == fmt.Sprintf("%d", team.Id)
+	}
+	if !found {
+		t.Errorf("expected a grant to the new team %d, got %v", team.Id, grants)
+	}
+}
+
+func TestResolveMissingTeamListsOncePerTTL(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	client := newTestConnector(ctx, t, server).client
+
+	// e.g. audit logs naming a team that was deleted since
+	for i := 0; i < 3; i++ {
+		if _, _, err := client.ResolveTeamID(ctx, "acme", "deleted"); status.Code(err) != codes.NotFound {
+			t.Fatalf("expected a missing team to be not found, got %v", err)
+		}
+	}
+
+	teamLists := 0
+	for _, req := range server.Requests() {
+		if req == "GET /v2/orgs/acme/groups" {
+			teamLists++
+		}
+	}
+	if teamLists != 2 {
+		t.Errorf("expected the teams to be listed and listed again once for the missing team, got %d lists", teamLists)
+	}
+}
+
+func TestRepositoryCollaboratorGrants(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	// collaborators used to get read and write access, without a permission reported
+	fixture.Users[0].Repositories[0].Collaborators["carol"] = ""
+	server := dockerhubtest.NewServer(t, fixture)
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	nr, err := namespaceResource(ctx, &dockerhub.User{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr, err := repositoryResource(ctx, &dockerhub.Repository{Name: "dotfiles"}, nr.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grants, _, _, err := r.Grants(ctx, rr, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, g := range grants {
+		got[g.Principal.Id.Resource] = entitlementSlug(g.Entitlement)
+	}
+
+	want := map[string]string{
+		server.UserID("bob"):   readPermission,
+		server.UserID("carol"): readAndWritePermission,
+		server.UserID("dave"):  readAndWritePermission,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected grants %v, got %v", want, got)
+	}
+	for id, permission := range want {
+		if got[id] != permission {
+			t.Errorf("expected %s to have %s permission, got %q", id, permission, got[id])
+		}
+	}
+}
+
+func TestRepositoryCollaboratorsLookedUpOnce(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Users[0].Repositories = append(fixture.Users[0].Repositories, dockerhubtest.Repository{
+		Name: "notes", Collaborators: map[string]string{"bob": "write"},
+	})
+	server := dockerhubtest.NewServer(t, fixture)
+	client := newTestConnector(ctx, t, server).client
+	r := repositoryBuilder(client, nil)
+
+	nr, err := namespaceResource(ctx, &dockerhub.User{Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, repo := range []string{"dotfiles", "notes"} {
+		rr, err := repositoryResource(ctx, &dockerhub.Repository{Name: repo}, nr.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, _, err := r.Grants(ctx, rr, &pagination.Token{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u := userBuilder(client, nil)
+	token := ""
+	for {
+		_, next, _, err := u.List(ctx, nil, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	lookups := 0
+	for _, req := range server.Requests() {
+		if req == "GET /v2/users/bob" {
+			lookups++
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected bob to be looked up once, got %d lookups", lookups)
+	}
+}
+
+func TestRepositoryGrantRejectsOtherPrincipals(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+	e := testRepositoryEntitlement(ctx, t, "acme", "web", readPermission)
+
+	for _, principal := range []*v2.Resource{
+		{Id: &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: server.UserID("bob")}},
+		{Id: &v2.ResourceId{ResourceType: resourceTypeOrgAccessToken.Id, Resource: server.OrgAccessTokenID("acme", "ci")}},
+	} {
+		if _, err := r.Grant(ctx, principal, e); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected granting to a %s to be rejected, got %v", principal.Id.ResourceType, err)
+		}
+
+		if _, err := r.Revoke(ctx, grant.NewGrant(e.Resource, readPermission, principal)); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected revoking from a %s to be rejected, got %v", principal.Id.ResourceType, err)
+		}
+	}
+}
+
+func TestRepositoryCreate(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+	acme := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}
+
+	created, _, err := r.Create(ctx, &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeRepository.Id},
+		ParentResourceId: acme,
+		DisplayName:      "Worker",
+		Description:      "Background jobs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Id.Resource != "acme/worker" {
+		t.Errorf("expected the repository to be named acme/worker, got %s", created.Id.Resource)
+	}
+	if len(created.Annotations) != 0 {
+		t.Errorf("expected no annotations on the repository, got %v", created.Annotations)
+	}
+	if repo, ok := server.Repository("acme", "worker"); !ok || !repo.Private || repo.Description != "Background jobs" {
+		t.Errorf("expected a private worker repository, got %v", repo)
+	}
+
+	_, _, err = r.Create(ctx, &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "docs"},
+		ParentResourceId: acme,
+		Description:      "[public] User guides",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo, ok := server.Repository("acme", "docs"); !ok || repo.Private || repo.Description != "User guides" {
+		t.Errorf("expected a public docs repository without the tag in its description, got %v", repo)
+	}
+
+	_, _, err = r.Create(ctx, &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "notes"},
+		ParentResourceId: acme,
+		Description:      "[private]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo, ok := server.Repository("acme", "notes"); !ok || !repo.Private || repo.Description != "" {
+		t.Errorf("expected a private notes repository without description, got %v", repo)
+	}
+
+	_, _, err = r.Create(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "api"}, ParentResourceId: acme})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected creating an existing repository to fail, got %v", err)
+	}
+
+	_, _, err = r.Create(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "globex/tools"}, ParentResourceId: acme})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected creating a repository of another namespace to fail, got %v", err)
+	}
+}
+
+func TestRepositoryDelete(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Organizations[1].Repositories = append(fixture.Organizations[1].Repositories, dockerhubtest.Repository{Name: "api"})
+	server := dockerhubtest.NewServer(t, fixture)
+	r := repositoryBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	if _, err := r.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "acme/web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := server.Repository("acme", "web"); ok {
+		t.Error("expected web to be deleted")
+	}
+
+	_, err := r.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "acme/web"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected deleting web twice to fail with not found, got %v", err)
+	}
+
+	// IDs synced before they were qualified with the namespace are looked up, api exists in acme and globex
+	_, err = r.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "api"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected deleting an ambiguous repository to fail, got %v", err)
+	}
+	if _, ok := server.Repository("acme", "api"); !ok {
+		t.Error("expected api to be kept")
+	}
+
+	if _, err := r.Delete(ctx, &v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: "acme/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Repository("acme", "api"); ok {
+		t.Error("expected acme/api to be deleted")
+	}
+	if _, ok := server.Repository("globex", "api"); !ok {
+		t.Error("expected globex/api to be kept")
+	}
+}
+
+func TestParseRepositoryId(t *testing.T) {
+	acme := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}
+
+	tests := []struct {
+		name      string
+		id        string
+		parentId  *v2.ResourceId
+		namespace string
+		repo      string
+		wantErr   bool
+	}{
+		{name: "qualified", id: "globex/api", parentId: acme, namespace: "globex", repo: "api"},
+		{name: "qualified without parent", id: "acme/api", namespace: "acme", repo: "api"},
+		{name: "legacy", id: "api", parentId: acme, namespace: "acme", repo: "api"},
+		{name: "legacy without parent", id: "api", wantErr: true},
+		{name: "missing name", id: "acme/", parentId: acme, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			namespace, repo, err := parseRepositoryId(&v2.ResourceId{ResourceType: resourceTypeRepository.Id, Resource: tc.id}, tc.parentId)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected %q to be rejected", tc.id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if namespace != tc.namespace || repo != tc.repo {
+				t.Errorf("expected %s/%s, got %s/%s", tc.namespace, tc.repo, namespace, repo)
+			}
+		})
+	}
+}
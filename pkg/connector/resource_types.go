@@ -15,6 +15,11 @@ var (
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
 		Annotations: annotationsForUserResourceType(),
 	}
+	resourceTypeInvitation = &v2.ResourceType{
+		Id:          "invitation",
+		DisplayName: "Invitation",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+	}
 	resourceTypeTeam = &v2.ResourceType{
 		Id:          "team",
 		DisplayName: "Team",
@@ -22,8 +27,22 @@ var (
 			v2.ResourceType_TRAIT_GROUP,
 		},
 	}
+	resourceTypeNamespace = &v2.ResourceType{
+		Id:          "namespace",
+		DisplayName: "Personal Namespace",
+	}
 	resourceTypeRepository = &v2.ResourceType{
 		Id:          "repository",
 		DisplayName: "Repository",
 	}
+	resourceTypeAccessToken = &v2.ResourceType{
+		Id:          "access_token",
+		DisplayName: "Access Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
+	resourceTypeOrgAccessToken = &v2.ResourceType{
+		Id:          "org_access_token",
+		DisplayName: "Organization Access Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
 )
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -11,6 +12,8 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -20,6 +23,7 @@ const (
 type teamResourceType struct {
 	resourceType *v2.ResourceType
 	client       *dockerhub.Client
+	orgs         []string
 }
 
 func (t *teamResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -70,9 +74,10 @@ func (t *teamResourceType) List(ctx context.Context, parentId *v2.ResourceId, pT
 		Page: page,
 	}
 
-	teams, nextPage, err := t.client.ListTeams(ctx, parentId.Resource, &paginationOpts)
+	teams, nextPage, rateLimitData, err := t.client.ListTeams(ctx, parentId.Resource, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list teams: %w", err)
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list teams: %w", err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -92,7 +97,7 @@ func (t *teamResourceType) List(ctx context.Context, parentId *v2.ResourceId, pT
 		rv = append(rv, tr)
 	}
 
-	return rv, next, nil, nil
+	return rv, next, annos, nil
 }
 
 // Entitlements returns always one membership entitlement representing that a user is a member of a team.
@@ -117,17 +122,11 @@ func (t *teamResourceType) Entitlements(_ context.Context, resource *v2.Resource
 
 // Grants returns a slice of grants for each member that team contain.
 func (t *teamResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	teamGroupTrait, err := rs.GetGroupTrait(resource)
+	orgSlug, teamSlug, err := teamSlugs(resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	teamSlug, ok := rs.GetProfileStringValue(teamGroupTrait.Profile, "team_name")
-	if !ok {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to get team name from profile")
-	}
-
-	orgSlug := resource.ParentResourceId.Resource
 	bag, page, err := parsePageToken(pToken.Token, resource.Id)
 	if err != nil {
 		return nil, "", nil, err
@@ -138,9 +137,10 @@ func (t *teamResourceType) Grants(ctx context.Context, resource *v2.Resource, pT
 		Page: page,
 	}
 
-	members, nextPage, err := t.client.ListTeamMembers(ctx, orgSlug, teamSlug, &paginationOpts)
+	members, nextPage, rateLimitData, err := t.client.ListTeamMembers(ctx, orgSlug, teamSlug, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list team members: %w", err)
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list team members: %w", err)
 	}
 
 	next, err := bag.NextToken(nextPage)
@@ -150,21 +150,176 @@ func (t *teamResourceType) Grants(ctx context.Context, resource *v2.Resource, pT
 
 	var rv []*v2.Grant
 	for _, member := range members {
-		memberCopy := member
-		ur, err := userResource(ctx, &memberCopy, resource.Id)
-		if err != nil {
-			return nil, "", nil, err
+		rv = append(rv, grant.NewGrant(resource, teamMembership, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: member.Id}))
+	}
+
+	return rv, next, annos, nil
+}
+
+// Grant adds the user to the team. The user has to be a member of the organization already.
+func (t *teamResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("dockerhub-connector: only users can be added to teams")
+	}
+
+	orgSlug, teamSlug, err := teamSlugs(entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := userLogin(ctx, t.client, principal, orgSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitData, err := t.client.AddTeamMember(ctx, orgSlug, teamSlug, username)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			annos.Append(&v2.GrantAlreadyExists{})
+			return annos, nil
+		}
+
+		return annos, fmt.Errorf("dockerhub-connector: failed to add %s to team %s: %w", username, teamSlug, err)
+	}
+
+	return annos, nil
+}
+
+// Revoke removes the user from the team, the user stays a member of the organization.
+func (t *teamResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	principal := grant.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("dockerhub-connector: only users can be removed from teams")
+	}
+
+	orgSlug, teamSlug, err := teamSlugs(grant.Entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := userLogin(ctx, t.client, principal, orgSlug)
+	if err != nil {
+		// users no longer in the organization are not in any of its teams either
+		if status.Code(err) == codes.NotFound {
+			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+		}
+
+		return nil, err
+	}
+
+	rateLimitData, err := t.client.RemoveTeamMember(ctx, orgSlug, teamSlug, username)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			annos.Append(&v2.GrantAlreadyRevoked{})
+			return annos, nil
+		}
+
+		return annos, fmt.Errorf("dockerhub-connector: failed to remove %s from team %s: %w", username, teamSlug, err)
+	}
+
+	return annos, nil
+}
+
+// Create creates a team in the parent organization, named after the display name of the resource.
+func (t *teamResourceType) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != resourceTypeOrg.Id {
+		return nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: teams can only be created in an organization")
+	}
+
+	name := resource.DisplayName
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: team name is required")
+	}
+
+	orgSlug := resource.ParentResourceId.Resource
+	team, rateLimitData, err := t.client.CreateTeam(ctx, orgSlug, name, resource.Description)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("dockerhub-connector: failed to create team %s in %s: %w", name, orgSlug, err)
+	}
+
+	tr, err := teamResource(ctx, team, resource.ParentResourceId)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return tr, annos, nil
+}
+
+// Delete deletes the team. Team IDs don't tell which organization the team belongs to,
+// so it is looked up among the teams of the synced organizations first.
+func (t *teamResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	teamId, err := strconv.Atoi(resourceId.Resource)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "dockerhub-connector: invalid team id %s", resourceId.Resource)
+	}
+
+	orgSlug, team, err := t.findTeam(ctx, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	if team == nil {
+		return nil, status.Errorf(codes.NotFound, "dockerhub-connector: team %d not found", teamId)
+	}
+
+	rateLimitData, err := t.client.DeleteTeam(ctx, orgSlug, team.Name)
+	annos := annotationsWithRateLimit(rateLimitData)
+	if err != nil {
+		return annos, fmt.Errorf("dockerhub-connector: failed to delete team %s from %s: %w", team.Name, orgSlug, err)
+	}
+
+	return annos, nil
+}
+
+// findTeam returns the team with the given ID and the organization it belongs to,
+// or a nil team when none of the synced organizations has it.
+func (t *teamResourceType) findTeam(ctx context.Context, teamId int) (string, *dockerhub.Team, error) {
+	orgSlugs, err := syncedOrganizations(ctx, t.client, t.orgs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, orgSlug := range orgSlugs {
+		for team, err := range dockerhub.ListAll[dockerhub.Team](ctx, t.client, 100, dockerhub.TeamsEndpoint, orgSlug) {
+			if err != nil {
+				return "", nil, fmt.Errorf("dockerhub-connector: failed to list teams of %s: %w", orgSlug, err)
+			}
+
+			if team.Id == teamId {
+				return orgSlug, &team, nil
+			}
 		}
+	}
+
+	return "", nil, nil
+}
 
-		rv = append(rv, grant.NewGrant(resource, teamMembership, ur.Id))
+// teamSlugs returns the organization and the name of the team, which DockerHub uses to address teams.
+func teamSlugs(resource *v2.Resource) (string, string, error) {
+	if resource.ParentResourceId == nil {
+		return "", "", fmt.Errorf("dockerhub-connector: team %s has no parent organization", resource.Id.Resource)
+	}
+
+	teamGroupTrait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return "", "", err
+	}
+
+	teamSlug, ok := rs.GetProfileStringValue(teamGroupTrait.Profile, "team_name")
+	if !ok {
+		return "", "", fmt.Errorf("dockerhub-connector: failed to get team name from profile")
 	}
 
-	return rv, next, nil, nil
+	return resource.ParentResourceId.Resource, teamSlug, nil
 }
 
-func teamBuilder(client *dockerhub.Client) *teamResourceType {
+func teamBuilder(client *dockerhub.Client, orgs []string) *teamResourceType {
 	return &teamResourceType{
 		resourceType: resourceTypeTeam,
 		client:       client,
+		orgs:         orgs,
 	}
 }
@@ -0,0 +1,160 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testTeamEntitlement(ctx context.Context, t *testing.T, server *dockerhubtest.Server, orgSlug, teamName string) *v2.Entitlement {
+	t.Helper()
+
+	tr, err := teamResource(ctx, &dockerhub.Team{Id: server.TeamID(orgSlug, teamName), Name: teamName}, &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgSlug})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entitlements, _, _, err := teamBuilder(nil, nil).Entitlements(ctx, tr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entitlements[0]
+}
+
+func TestTeamGrant(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	tt := teamBuilder(newTestConnector(ctx, t, server).client, nil)
+	ops := testTeamEntitlement(ctx, t, server, "acme", "ops")
+
+	annos, err := tt.Grant(ctx, testUserResource(ctx, t, server, "bob"), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertExistsAnnotation(t, annos, false)
+	if !slices.Contains(server.TeamMembers("acme", "ops"), "bob") {
+		t.Error("expected bob to be added to ops")
+	}
+
+	annos, err = tt.Grant(ctx, testUserResource(ctx, t, server, "bob"), ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertExistsAnnotation(t, annos, true)
+}
+
+func TestTeamGrantRequiresOrgMembership(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Users = append(fixture.Users, dockerhubtest.User{Username: "dave"})
+	server := dockerhubtest.NewServer(t, fixture)
+	tt := teamBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	_, err := tt.Grant(ctx, testUserResource(ctx, t, server, "dave"), testTeamEntitlement(ctx, t, server, "acme", "ops"))
+	if err == nil {
+		t.Fatal("expected adding a user outside of the organization to fail")
+	}
+}
+
+func TestTeamRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	tt := teamBuilder(newTestConnector(ctx, t, server).client, nil)
+	developers := testTeamEntitlement(ctx, t, server, "acme", "developers")
+
+	// grants synced before the principal profile was included only carry its ID
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: server.UserID("bob")}}
+	g := grant.NewGrant(developers.Resource, teamMembership, principal)
+
+	annos, err := tt.Revoke(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevokedAnnotation(t, annos, false)
+	if slices.Contains(server.TeamMembers("acme", "developers"), "bob") {
+		t.Error("expected bob to be removed from developers")
+	}
+	if _, ok := server.Members("acme")["bob"]; !ok {
+		t.Error("expected bob to stay a member of acme")
+	}
+
+	annos, err = tt.Revoke(ctx, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRevokedAnnotation(t, annos, true)
+}
+
+func TestTeamCreate(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	tt := teamBuilder(newTestConnector(ctx, t, server).client, nil)
+	acme := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}
+
+	resource := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeTeam.Id},
+		ParentResourceId: acme,
+		DisplayName:      "project-x",
+		Description:      "Project X",
+	}
+
+	created, _, err := tt.Create(ctx, resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := server.TeamID("acme", "project-x")
+	if id == 0 {
+		t.Fatal("expected project-x to be created in acme")
+	}
+	if created.Id.Resource != strconv.Itoa(id) || created.ParentResourceId.Resource != "acme" {
+		t.Errorf("unexpected resource for the created team: %v", created)
+	}
+
+	if _, _, err := tt.Create(ctx, resource); err == nil {
+		t.Error("expected creating a team twice to fail")
+	}
+
+	resource.ParentResourceId = nil
+	if _, _, err := tt.Create(ctx, resource); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected invalid argument without an organization, got %v", err)
+	}
+}
+
+func TestTeamDelete(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	tt := teamBuilder(newTestConnector(ctx, t, server).client, nil)
+	teamId := &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: strconv.Itoa(server.TeamID("acme", "ops"))}
+
+	if _, err := tt.Delete(ctx, teamId); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.TeamID("acme", "ops") != 0 {
+		t.Error("expected ops to be deleted")
+	}
+	if _, ok := server.RepositoryPermissions("acme", "api")["ops"]; ok {
+		t.Error("expected ops to lose access to api")
+	}
+	if _, ok := server.Members("acme")["carol"]; !ok {
+		t.Error("expected carol to stay a member of acme")
+	}
+
+	if _, err := tt.Delete(ctx, teamId); status.Code(err) != codes.NotFound {
+		t.Errorf("expected deleting a team twice to fail with not found, got %v", err)
+	}
+}
@@ -2,25 +2,34 @@ package connector
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/conductorone/baton-dockerhub/pkg/dockerhub"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type userResourceType struct {
 	resourceType *v2.ResourceType
 	client       *dockerhub.Client
+	orgs         []string
 }
 
 func (u *userResourceType) ResourceType(ctx context.Context) *v2.ResourceType {
 	return resourceTypeUser
 }
 
-// Create a new connector resource for an DockerHub user.
-func userResource(ctx context.Context, user *dockerhub.User, parentId *v2.ResourceId) (*v2.Resource, error) {
+// Create a new connector resource for an DockerHub user. Users are top-level resources identified by their
+// Docker ID, organizations and teams only link to them through grants.
+func userResource(ctx context.Context, user *dockerhub.User) (*v2.Resource, error) {
 	firstName, lastName := splitFullName(user.FullName)
 
 	profile := map[string]interface{}{
@@ -41,7 +50,6 @@ func userResource(ctx context.Context, user *dockerhub.User, parentId *v2.Resour
 		resourceTypeUser,
 		user.Id,
 		userTraitOptions,
-		rs.WithParentResourceID(parentId),
 	)
 
 	if err != nil {
@@ -51,38 +59,90 @@ func userResource(ctx context.Context, user *dockerhub.User, parentId *v2.Resour
 	return resource, nil
 }
 
-// List returns all the users from the database as resource objects.
+// List returns the members of all synced organizations as resource objects, followed by the users of the
+// personal namespace of the connector account. Every user is listed from a single source, so that all of
+// their data comes from the same record: members from the first synced organization they belong to, the
+// users of the namespace only when they aren't a member of any synced organization.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (u *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	if parentId == nil {
+	if parentId != nil {
 		return nil, "", nil, nil
 	}
 
-	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
-	if err != nil {
+	bag := &pagination.Bag{}
+	if err := bag.Unmarshal(pToken.Token); err != nil {
 		return nil, "", nil, err
 	}
 
+	// the bag holds a state per organization left to list, the current one on top
+	if bag.Current() == nil {
+		orgSlugs, err := syncedOrganizations(ctx, u.client, u.orgs)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		// organization access tokens don't belong to a user with a personal namespace
+		if u.client.ScopedOrganization() == "" {
+			bag.Push(pagination.PageState{ResourceTypeID: resourceTypeNamespace.Id})
+		}
+
+		for i := len(orgSlugs) - 1; i >= 0; i-- {
+			bag.Push(pagination.PageState{ResourceTypeID: resourceTypeOrg.Id, ResourceID: orgSlugs[i]})
+		}
+
+		if bag.Current() == nil {
+			return nil, "", nil, nil
+		}
+	}
+
+	if bag.ResourceTypeID() == resourceTypeNamespace.Id {
+		return u.listNamespaceUsers(ctx, bag)
+	}
+
+	orgSlug := bag.ResourceID()
 	paginationOpts := dockerhub.PaginationVars{
 		Size: ResourcesPageSize,
-		Page: page,
+		Page: bag.PageToken(),
+	}
+
+	orgSlugs, err := syncedOrganizations(ctx, u.client, u.orgs)
+	if err != nil {
+		return nil, "", nil, err
 	}
 
-	users, nextPage, err := u.client.ListUsers(ctx, parentId.Resource, &paginationOpts)
+	// organizations listed before this one already returned their members
+	if i := slices.Index(orgSlugs, orgSlug); i >= 0 {
+		orgSlugs = orgSlugs[:i]
+	}
+
+	users, nextPage, rateLimitData, err := u.client.ListUsers(ctx, orgSlug, &paginationOpts)
+	annos := annotationsWithRateLimit(rateLimitData)
 	if err != nil {
+		return nil, "", annos, fmt.Errorf("dockerhub-connector: failed to list users under organization %s: %w", orgSlug, err)
+	}
+
+	if err := bag.Next(nextPage); err != nil {
 		return nil, "", nil, err
 	}
 
-	next, err := bag.NextToken(nextPage)
+	next, err := bag.Marshal()
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	var rv []*v2.Resource
 	for _, user := range users {
+		member, err := u.memberOfAny(ctx, orgSlugs, user.Username)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if member {
+			continue
+		}
+
 		userCopy := user
 
-		ur, err := userResource(ctx, &userCopy, parentId)
+		ur, err := userResource(ctx, &userCopy)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -90,7 +150,113 @@ func (u *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, pT
 		rv = append(rv, ur)
 	}
 
-	return rv, next, nil, nil
+	return rv, next, annos, nil
+}
+
+// listNamespaceUsers returns the user the connector runs as and the collaborators of the repositories in
+// their personal namespace, a page of repositories at a time. Users who are members of a synced organization
+// are left to the organization, whose member records carry the email the public profile of a user lacks.
+func (u *userResourceType) listNamespaceUsers(ctx context.Context, bag *pagination.Bag) ([]*v2.Resource, string, annotations.Annotations, error) {
+	owner, rateLimitData, err := u.client.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get current user: %w", err)
+	}
+
+	orgSlugs, err := syncedOrganizations(ctx, u.client, u.orgs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var users []*dockerhub.User
+	if bag.PageToken() == "" {
+		member, err := u.memberOfAny(ctx, orgSlugs, owner.Username)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if !member {
+			users = append(users, owner)
+		}
+	}
+
+	paginationOpts := dockerhub.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: bag.PageToken(),
+	}
+
+	repositories, nextPage, rateLimitData, err := u.client.ListRepositories(ctx, owner.Username, &paginationOpts)
+	if err != nil {
+		return nil, "", annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to list repositories of %s: %w", owner.Username, err)
+	}
+
+	usernames := map[string]struct{}{owner.Username: {}}
+	for _, repository := range repositories {
+		for collaborator, err := range dockerhub.ListAll[dockerhub.Collaborator](ctx, u.client, 100, dockerhub.CollaboratorsEndpoint, owner.Username, repository.Name) {
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to list collaborators of repository %s/%s: %w", owner.Username, repository.Name, err)
+			}
+
+			if _, ok := usernames[collaborator.Username]; ok {
+				continue
+			}
+			usernames[collaborator.Username] = struct{}{}
+
+			member, err := u.memberOfAny(ctx, orgSlugs, collaborator.Username)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			if member {
+				continue
+			}
+
+			user, _, err := u.client.LookupUser(ctx, collaborator.Username)
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					continue
+				}
+
+				return nil, "", nil, fmt.Errorf("dockerhub-connector: failed to get user %s: %w", collaborator.Username, err)
+			}
+
+			users = append(users, user)
+		}
+	}
+
+	if err := bag.Next(nextPage); err != nil {
+		return nil, "", nil, err
+	}
+
+	next, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	for _, user := range users {
+		ur, err := userResource(ctx, user)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, ur)
+	}
+
+	return rv, next, annotationsWithRateLimit(rateLimitData), nil
+}
+
+// memberOfAny reports whether the user is a member of any of the organizations.
+func (u *userResourceType) memberOfAny(ctx context.Context, orgSlugs []string, username string) (bool, error) {
+	for _, orgSlug := range orgSlugs {
+		member, _, err := u.client.IsOrganizationMember(ctx, orgSlug, username)
+		if err != nil {
+			return false, fmt.Errorf("dockerhub-connector: failed to list members of organization %s: %w", orgSlug, err)
+		}
+
+		if member {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -103,9 +269,175 @@ func (u *userResourceType) Grants(ctx context.Context, resource *v2.Resource, pT
 	return nil, "", nil, nil
 }
 
-func userBuilder(client *dockerhub.Client) *userResourceType {
+// CreateAccount invites the user, by Docker ID or email, to an organization. The profile selects the
+// organization, the role (member by default) and optionally the teams the user joins once the
+// invitation is accepted. The organization can be omitted when only a single one is synced.
+// Members matching the Docker ID or any of the emails are returned as they are, and teams the user has
+// a pending invitation to are not invited to again.
+func (u *userResourceType) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	credentialOptions *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	invitee := accountInfo.GetLogin()
+	var identities []string
+	if invitee != "" {
+		identities = append(identities, invitee)
+	}
+	for _, email := range accountInfo.GetEmails() {
+		if email.GetAddress() == "" {
+			continue
+		}
+		identities = append(identities, email.GetAddress())
+
+		if accountInfo.GetLogin() == "" && (invitee == "" || email.GetIsPrimary()) {
+			invitee = email.GetAddress()
+		}
+	}
+
+	if invitee == "" {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, "dockerhub-connector: a Docker ID or an email is required to invite a user")
+	}
+
+	params, err := u.accountParams(accountInfo.GetProfile())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	member, rateLimitData, err := u.client.FindOrganizationMember(ctx, params.org, identities)
+	if err != nil {
+		return nil, nil, annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to get member %s of organization %s: %w", invitee, params.org, err)
+	}
+
+	// already in the organization, there is nobody to invite
+	if member != nil {
+		ur, err := userResource(ctx, member)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		return &v2.CreateAccountResponse_SuccessResult{Resource: ur}, nil, annotationsWithRateLimit(rateLimitData), nil
+	}
+
+	invites, rateLimitData, err := u.client.ListInvites(ctx, params.org)
+	if err != nil {
+		return nil, nil, annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to list invitations of organization %s: %w", params.org, err)
+	}
+
+	teams := params.teams
+	if len(teams) == 0 {
+		teams = []string{""}
+	}
+
+	// DockerHub invites to a single team at a time, every team gets its own invitation. Teams the user
+	// already has a pending invitation to, e.g. from an attempt that failed on a later team, keep theirs.
+	var sent []*dockerhub.Invite
+	reinvited := true
+	for _, team := range teams {
+		if invite := pendingInvite(invites, identities, team); invite != nil {
+			sent = append(sent, invite)
+			continue
+		}
+
+		var results []dockerhub.InviteeResult
+		results, rateLimitData, err = u.client.InviteToOrganization(ctx, params.org, team, params.role, invitee)
+		if err != nil {
+			return nil, nil, annotationsWithRateLimit(rateLimitData), fmt.Errorf("dockerhub-connector: failed to invite %s to organization %s: %w", invitee, params.org, err)
+		}
+
+		invite, err := sentInvite(results, invitee, params.org)
+		if err != nil {
+			return nil, nil, annotationsWithRateLimit(rateLimitData), err
+		}
+
+		sent = append(sent, invite)
+		reinvited = false
+	}
+
+	message := fmt.Sprintf("%s was invited to the DockerHub organization %s and becomes a member once the invitation is accepted", invitee, params.org)
+	if reinvited {
+		message = fmt.Sprintf("%s was already invited to the DockerHub organization %s and becomes a member once the invitation is accepted", invitee, params.org)
+	}
+
+	result := &v2.CreateAccountResponse_ActionRequiredResult{Message: message}
+	if sent[0] != nil {
+		result.Resource, err = invitationResource(ctx, sent[0], &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: params.org})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return result, nil, annotationsWithRateLimit(rateLimitData), nil
+}
+
+// CreateAccountCapabilityDetails advertises that accounts are created without credentials,
+// invited users set up their DockerHub account themselves.
+func (u *userResourceType) CreateAccountCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+type accountParams struct {
+	org   string
+	role  string
+	teams []string
+}
+
+// accountParams reads the organization, role and teams of an invitation from the account profile.
+func (u *userResourceType) accountParams(profile *structpb.Struct) (*accountParams, error) {
+	params := &accountParams{role: roleMember}
+	values := profile.AsMap()
+
+	if org, ok := values["organization"].(string); ok {
+		params.org = org
+	}
+
+	if params.org == "" {
+		switch {
+		case u.client.ScopedOrganization() != "":
+			params.org = u.client.ScopedOrganization()
+		case len(u.orgs) == 1:
+			params.org = u.orgs[0]
+		default:
+			return nil, status.Error(codes.InvalidArgument, "dockerhub-connector: organization to invite the user to is required")
+		}
+	}
+
+	if role, ok := values["role"].(string); ok && role != "" {
+		params.role = strings.ToLower(role)
+	}
+
+	if !slices.Contains(userRoles, params.role) {
+		return nil, status.Errorf(codes.InvalidArgument, "dockerhub-connector: invalid organization role %s", params.role)
+	}
+
+	// teams are either a list or a comma separated string
+	switch teams := values["teams"].(type) {
+	case []interface{}:
+		for _, team := range teams {
+			if name, ok := team.(string); ok && name != "" {
+				params.teams = append(params.teams, name)
+			}
+		}
+	case string:
+		for _, name := range strings.Split(teams, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				params.teams = append(params.teams, name)
+			}
+		}
+	}
+
+	return params, nil
+}
+
+func userBuilder(client *dockerhub.Client, orgs []string) *userResourceType {
 	return &userResourceType{
 		resourceType: resourceTypeUser,
 		client:       client,
+		orgs:         orgs,
 	}
 }
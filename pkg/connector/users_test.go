@@ -0,0 +1,266 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conductorone/baton-dockerhub/pkg/dockerhub/dockerhubtest"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func testAccountInfo(t *testing.T, login, email string, profile map[string]interface{}) *v2.AccountInfo {
+	t.Helper()
+
+	p, err := structpb.NewStruct(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &v2.AccountInfo{Login: login, Profile: p}
+	if email != "" {
+		info.Emails = []*v2.AccountInfo_Email{{Address: email, IsPrimary: true}}
+	}
+
+	return info
+}
+
+func TestUserList(t *testing.T) {
+	ctx := context.Background()
+	fixture := testFixture()
+	fixture.Organizations[1].Members = append(fixture.Organizations[1].Members, dockerhubtest.Member{Username: "carol", Role: "member"})
+	server := dockerhubtest.NewServer(t, fixture)
+	u := userBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	listed := map[string]int{}
+	emails := map[string][]string{}
+	token := ""
+	for {
+		users, next, _, err := u.List(ctx, nil, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, user := range users {
+			if user.ParentResourceId != nil {
+				t.Errorf("expected %s to be a top-level resource, got parent %v", user.DisplayName, user.ParentResourceId)
+			}
+			listed[user.Id.Resource]++
+
+			userTrait, err := rs.GetUserTrait(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, email := range userTrait.Emails {
+				emails[user.Id.Resource] = append(emails[user.Id.Resource], email.Address)
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	// alice and carol are members of acme and globex, alice also owns the personal namespace and bob
+	// collaborates on it, every user comes from a single source
+	for _, username := range []string{"alice", "bob", "carol"} {
+		if n := listed[server.UserID(username)]; n != 1 {
+			t.Errorf("expected %s to be listed once, got %d", username, n)
+		}
+	}
+	// public profiles lack emails, members keep the one of the member record
+	for _, username := range []string{"alice", "bob"} {
+		if got := emails[server.UserID(username)]; len(got) != 1 || got[0] != username+"@example.com" {
+			t.Errorf("expected %s to be listed with their email, got %v", username, got)
+		}
+	}
+	// dave only collaborates on a repository in the personal namespace of alice
+	if listed[server.UserID("dave")] == 0 {
+		t.Error("expected dave to be listed")
+	}
+	if want := 4 + int(extraMembers); len(listed) != want {
+		t.Errorf("expected %d users, got %d", want, len(listed))
+	}
+
+	users, _, _, err := u.List(ctx, &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "acme"}, &pagination.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("expected no users under organizations, got %d", len(users))
+	}
+}
+
+func TestCreateAccount(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	u := userBuilder(newTestConnector(ctx, t, server).client, nil)
+
+	t.Run("invites email into teams", func(t *testing.T) {
+		info := testAccountInfo(t, "", "erin@example.com", map[string]interface{}{
+			"organization": "acme",
+			"role":         "Editor",
+			"teams":        []interface{}{"developers", "ops"},
+		})
+
+		result, _, _, err := u.CreateAccount(ctx, info, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invited, ok := result.(*v2.CreateAccountResponse_ActionRequiredResult)
+		if !ok {
+			t.Fatalf("expected an action required result, got %T", result)
+		}
+		if invited.Resource.GetId().GetResourceType() != resourceTypeInvitation.Id || invited.Resource.DisplayName != "erin@example.com" {
+			t.Errorf("expected the invitation to be returned, got %v", invited.Resource)
+		}
+
+		invites := server.Invites("acme")
+		if len(invites) != 2 {
+			t.Fatalf("expected an invite per team, got %v", invites)
+		}
+		for i, team := range []string{"developers", "ops"} {
+			want := dockerhubtest.Invite{Invitee: "erin@example.com", Role: roleEditor, Team: team}
+			if invites[i] != want {
+				t.Errorf("expected invite %v, got %v", want, invites[i])
+			}
+		}
+	})
+
+	t.Run("existing member", func(t *testing.T) {
+		result, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "bob", "", map[string]interface{}{"organization": "acme"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		success, ok := result.(*v2.CreateAccountResponse_SuccessResult)
+		if !ok {
+			t.Fatalf("expected a success result, got %T", result)
+		}
+		if success.Resource.Id.Resource != server.UserID("bob") {
+			t.Errorf("expected the existing user to be returned, got %v", success.Resource.Id)
+		}
+	})
+
+	t.Run("existing member by email", func(t *testing.T) {
+		result, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "", "Carol@example.com", map[string]interface{}{"organization": "acme"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		success, ok := result.(*v2.CreateAccountResponse_SuccessResult)
+		if !ok {
+			t.Fatalf("expected a success result, got %T", result)
+		}
+		if success.Resource.Id.Resource != server.UserID("carol") {
+			t.Errorf("expected the existing user to be returned, got %v", success.Resource.Id)
+		}
+	})
+
+	t.Run("pending invitation", func(t *testing.T) {
+		before := len(server.Invites("acme"))
+
+		result, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "", "erin@example.com", map[string]interface{}{"organization": "acme"}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending, ok := result.(*v2.CreateAccountResponse_ActionRequiredResult)
+		if !ok {
+			t.Fatalf("expected an action required result, got %T", result)
+		}
+		if pending.Resource.GetId().GetResourceType() != resourceTypeInvitation.Id || pending.Resource.DisplayName != "erin@example.com" {
+			t.Errorf("expected the pending invitation to be returned, got %v", pending.Resource)
+		}
+		if after := len(server.Invites("acme")); after != before {
+			t.Errorf("expected no new invitation, got %d instead of %d", after, before)
+		}
+	})
+
+	t.Run("invites remaining teams", func(t *testing.T) {
+		bulkInvites := func() int {
+			n := 0
+			for _, req := range server.Requests() {
+				if req == "POST /v2/invites/bulk" {
+					n++
+				}
+			}
+			return n
+		}
+
+		// the invitation to the missing team fails after the one to developers was sent
+		_, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "", "grace@example.com", map[string]interface{}{
+			"organization": "acme",
+			"teams":        []interface{}{"developers", "missing"},
+		}), nil)
+		if err == nil {
+			t.Fatal("expected inviting to a missing team to fail")
+		}
+
+		before := bulkInvites()
+		_, _, _, err = u.CreateAccount(ctx, testAccountInfo(t, "", "grace@example.com", map[string]interface{}{
+			"organization": "acme",
+			"teams":        []interface{}{"developers", "ops"},
+		}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := bulkInvites() - before; n != 1 {
+			t.Errorf("expected only ops to be invited to again, got %d invitations", n)
+		}
+
+		var teams []string
+		for _, invite := range server.Invites("acme") {
+			if invite.Invitee == "grace@example.com" {
+				teams = append(teams, invite.Team)
+			}
+		}
+		if len(teams) != 2 || teams[0] != "developers" || teams[1] != "ops" {
+			t.Errorf("expected grace to be invited to developers and ops, got %v", teams)
+		}
+	})
+
+	t.Run("refused invitee", func(t *testing.T) {
+		_, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "nobody", "", map[string]interface{}{"organization": "acme"}), nil)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected an unknown Docker ID to be refused, got %v", err)
+		}
+	})
+
+	t.Run("requires organization", func(t *testing.T) {
+		_, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "frank", "", nil), nil)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected invalid argument, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown role", func(t *testing.T) {
+		_, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "frank", "", map[string]interface{}{"organization": "acme", "role": "admin"}), nil)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected invalid argument, got %v", err)
+		}
+	})
+}
+
+func TestCreateAccountDefaultsToSingleOrg(t *testing.T) {
+	ctx := context.Background()
+	server := dockerhubtest.NewServer(t, testFixture())
+	u := userBuilder(newTestConnector(ctx, t, server).client, []string{"acme"})
+
+	_, _, _, err := u.CreateAccount(ctx, testAccountInfo(t, "dave", "", map[string]interface{}{"teams": "ops"}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invites := server.Invites("acme")
+	if len(invites) != 1 || invites[0] != (dockerhubtest.Invite{Invitee: "dave", Role: roleMember, Team: "ops"}) {
+		t.Errorf("expected dave to be invited to ops as member, got %v", invites)
+	}
+}
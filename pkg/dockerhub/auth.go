@@ -0,0 +1,184 @@
+package dockerhub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Authenticator obtains the access token the client sends as a bearer token
+// with every DockerHub API request.
+type Authenticator interface {
+	// Authenticate exchanges the credentials for a new access token. It is called
+	// when the client is created and again whenever the API rejects the token.
+	Authenticate(ctx context.Context, c *Client) (*TokenResp, error)
+}
+
+// Refresher is implemented by authenticators whose login flow issues refresh
+// tokens that can be exchanged for a new access token.
+type Refresher interface {
+	Refresh(ctx context.Context, c *Client, refreshToken string) (*TokenResp, error)
+}
+
+type CredentialsReq struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type RefreshTokenReq struct {
+	Username     string `json:"username"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AccessTokenReq struct {
+	Identifier string `json:"identifier"`
+	Secret     string `json:"secret"`
+}
+
+type TwoFactorLoginReq struct {
+	Login2FAToken string `json:"login_2fa_token"`
+	Code          string `json:"code"`
+}
+
+type TokenResp struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// Login2FAToken is returned instead of the tokens above when the account has
+	// two-factor authentication enabled. It has to be exchanged together with
+	// a one-time code for the actual tokens.
+	Login2FAToken string `json:"login_2fa_token"`
+}
+
+type AccessTokenResp struct {
+	AccessToken string `json:"access_token"`
+}
+
+// PasswordAuth logs in with the username and password of a DockerHub account.
+// Accounts with two-factor authentication enabled also need the TOTP secret
+// of their authenticator app, so that the one-time codes can be generated.
+type PasswordAuth struct {
+	Username   string
+	Password   string
+	TOTPSecret string
+}
+
+func (a *PasswordAuth) Authenticate(ctx context.Context, c *Client) (*TokenResp, error) {
+	data, err := login(ctx, c, a.Username, a.Password)
+	if data == nil || data.Login2FAToken == "" {
+		return data, err
+	}
+
+	if a.TOTPSecret == "" {
+		return nil, fmt.Errorf(
+			"dockerhub-connector: account %s has two-factor authentication enabled, "+
+				"either configure the TOTP secret of the account or use a personal access token instead of the password",
+			a.Username,
+		)
+	}
+
+	code, err := generateTOTP(a.TOTPSecret, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("dockerhub-connector: failed to generate two-factor authentication code: %w", err)
+	}
+
+	var response TokenResp
+
+	err = c.requestToken(ctx, TwoFactorLoginEndpoint, TwoFactorLoginReq{Login2FAToken: data.Login2FAToken, Code: code}, &response)
+	if err != nil {
+		return nil, fmt.Errorf("dockerhub-connector: two-factor login failed: %w", err)
+	}
+
+	return &response, nil
+}
+
+func (a *PasswordAuth) Refresh(ctx context.Context, c *Client, refreshToken string) (*TokenResp, error) {
+	return refresh(ctx, c, a.Username, refreshToken)
+}
+
+// PersonalAccessTokenAuth logs in with a username and one of its personal access tokens.
+type PersonalAccessTokenAuth struct {
+	Username string
+	Token    string
+}
+
+func (a *PersonalAccessTokenAuth) Authenticate(ctx context.Context, c *Client) (*TokenResp, error) {
+	return login(ctx, c, a.Username, a.Token)
+}
+
+func (a *PersonalAccessTokenAuth) Refresh(ctx context.Context, c *Client, refreshToken string) (*TokenResp, error) {
+	return refresh(ctx, c, a.Username, refreshToken)
+}
+
+// OrganizationScoped is implemented by authenticators whose tokens grant access
+// to a single organization instead of the organizations of a user account.
+type OrganizationScoped interface {
+	ScopedOrganization() string
+}
+
+// OrgAccessTokenAuth exchanges an organization access token for an access token.
+// Organization access tokens are not tied to any user account.
+type OrgAccessTokenAuth struct {
+	Organization string
+	Token        string
+}
+
+func (a *OrgAccessTokenAuth) ScopedOrganization() string {
+	return a.Organization
+}
+
+func (a *OrgAccessTokenAuth) Authenticate(ctx context.Context, c *Client) (*TokenResp, error) {
+	var response AccessTokenResp
+
+	err := c.requestToken(ctx, AuthTokenEndpoint, AccessTokenReq{Identifier: a.Organization, Secret: a.Token}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResp{Token: response.AccessToken}, nil
+}
+
+// BearerTokenAuth uses a pre-issued access token as is. The token can't be
+// renewed, so requests fail once it expires.
+type BearerTokenAuth struct {
+	Token string
+}
+
+func (a *BearerTokenAuth) Authenticate(ctx context.Context, c *Client) (*TokenResp, error) {
+	if a.Token == "" {
+		return nil, fmt.Errorf("dockerhub-connector: bearer token is empty")
+	}
+
+	return &TokenResp{Token: a.Token}, nil
+}
+
+// login returns the parsed response alongside the error, since DockerHub rejects
+// the first step of a two-factor login with a 401 carrying the login_2fa_token.
+func login(ctx context.Context, c *Client, username, password string) (*TokenResp, error) {
+	var response TokenResp
+
+	err := c.requestToken(ctx, LoginEndpoint, CredentialsReq{Username: username, Password: password}, &response)
+	if err != nil {
+		if response.Login2FAToken != "" {
+			return &response, nil
+		}
+
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// refresh exchanges the refresh token of a previous login for a new access token. DockerHub doesn't
+// document refresh tokens: the login endpoint returns one next to the access token and accepts it back
+// in place of the password, as the refresh_token field of the login body. Since nothing guarantees that
+// this keeps working, the client falls back to a full login whenever the refresh fails.
+func refresh(ctx context.Context, c *Client, username, refreshToken string) (*TokenResp, error) {
+	var response TokenResp
+
+	err := c.requestToken(ctx, LoginEndpoint, RefreshTokenReq{Username: username, RefreshToken: refreshToken}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,115 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTwoFactorServer serves the two-step login of an account with two-factor authentication enabled:
+// the login is rejected with a login_2fa_token, which is exchanged together with the one-time code.
+func newTwoFactorServer(t *testing.T, secret string, twoFactorLogins *atomic.Int32) *url.URL {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		var req CredentialsReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username != "jdoe" || req.Password != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"detail":"Two-factor authentication required","login_2fa_token":"2fa-token"}`))
+	})
+	mux.HandleFunc("POST "+TwoFactorLoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		twoFactorLogins.Add(1)
+
+		var req TwoFactorLoginReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Login2FAToken != "2fa-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// accept the code of the previous period too, the test may run across a period boundary
+		now := time.Now()
+		current, _ := generateTOTP(secret, now)
+		previous, _ := generateTOTP(secret, now.Add(-totpPeriod))
+		if req.Code != current && req.Code != previous {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"token","refresh_token":"refresh-token"}`))
+	})
+	mux.HandleFunc("GET "+CurrentUserEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","username":"jdoe"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	baseUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return baseUrl
+}
+
+func TestPasswordAuthTwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+
+	var twoFactorLogins atomic.Int32
+	baseUrl := newTwoFactorServer(t, rfc6238Secret, &twoFactorLogins)
+
+	client, err := NewClient(ctx, &PasswordAuth{Username: "jdoe", Password: "secret", TOTPSecret: rfc6238Secret}, WithBaseURL(baseUrl))
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	if got := twoFactorLogins.Load(); got != 1 {
+		t.Errorf("expected a single two-factor login, got %d", got)
+	}
+
+	user, _, err := client.GetCurrentUser(ctx)
+	if err != nil {
+		t.Fatalf("expected the token of the two-factor login to be used: %v", err)
+	}
+	if user.Username != "jdoe" {
+		t.Errorf("expected jdoe, got %s", user.Username)
+	}
+}
+
+func TestPasswordAuthTwoFactorLoginWithoutSecret(t *testing.T) {
+	ctx := context.Background()
+
+	var twoFactorLogins atomic.Int32
+	baseUrl := newTwoFactorServer(t, rfc6238Secret, &twoFactorLogins)
+
+	_, err := NewClient(ctx, &PasswordAuth{Username: "jdoe", Password: "secret"}, WithBaseURL(baseUrl))
+	if err == nil {
+		t.Fatal("expected the login to fail without a TOTP secret")
+	}
+
+	if !strings.Contains(err.Error(), "two-factor authentication enabled") {
+		t.Errorf("expected the error to point at two-factor authentication, got %v", err)
+	}
+	if got := twoFactorLogins.Load(); got != 0 {
+		t.Errorf("expected no two-factor login without a code, got %d", got)
+	}
+}
@@ -2,65 +2,159 @@ package dockerhub
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const (
 	BaseDomain = "hub.docker.com"
 
-	LoginEndpoint = "/v2/users/login"
+	LoginEndpoint          = "/v2/users/login"
+	TwoFactorLoginEndpoint = "/v2/users/2fa-login"
+	AuthTokenEndpoint      = "/v2/auth/token"
 
-	OrgsEndpoint      = "/v2/orgs"
-	OrgDetailEndpoint = OrgsEndpoint + "/%s"
-	UsersEndpoint     = OrgsEndpoint + "/%s/members"
+	OrgsEndpoint       = "/v2/orgs"
+	OrgDetailEndpoint  = OrgsEndpoint + "/%s"
+	UsersEndpoint      = OrgsEndpoint + "/%s/members"
+	OrgMemberEndpoint  = UsersEndpoint + "/%s"
+	OrgInvitesEndpoint = OrgsEndpoint + "/%s/invitees"
 
-	CurrentUserEndpoint = "/v2/user"
-	UserEndpoint        = "/v2/users/%s"
-	UserOrgsEndpoint    = UserEndpoint + "/orgs"
+	OrgAccessTokensEndpoint = OrgsEndpoint + "/%s/access-tokens"
+	OrgAccessTokenEndpoint  = OrgAccessTokensEndpoint + "/%s"
+
+	InviteEndpoint      = "/v2/invites/%s"
+	BulkInvitesEndpoint = "/v2/invites/bulk"
+
+	CurrentUserEndpoint  = "/v2/user"
+	AccessTokensEndpoint = "/v2/access-tokens"
+	UserEndpoint         = "/v2/users/%s"
+	UserOrgsEndpoint     = UserEndpoint + "/orgs"
+
+	AuditLogsEndpoint = "/v2/auditlogs/%s"
 
 	TeamsEndpoint           = OrgsEndpoint + "/%s/groups"
 	TeamDetailEndpoint      = TeamsEndpoint + "/%s"
 	TeamMembersEndpoint     = TeamDetailEndpoint + "/members"
+	TeamMemberEndpoint      = TeamMembersEndpoint + "/%s"
 	TeamPermissionsEndpoint = TeamDetailEndpoint + "/repositories"
 
-	RepositoriesEndpoint  = "/v2/repositories/%s"
-	RepositoryPermissions = RepositoriesEndpoint + "/%s/groups"
+	CreateRepositoryEndpoint = "/v2/repositories/"
+	RepositoriesEndpoint     = "/v2/repositories/%s"
+	RepositoryEndpoint       = RepositoriesEndpoint + "/%s"
+	RepositoryPermissions    = RepositoriesEndpoint + "/%s/groups"
+	CollaboratorsEndpoint    = RepositoryEndpoint + "/collaborators"
+	RepositoryTeamPermission = RepositoryPermissions + "/%d"
 )
 
 type Client struct {
 	httpClient  *uhttp.BaseHttpClient
 	baseUrl     *url.URL
+	loginUrl    *url.URL
 	currentUser string
+	auth        Authenticator
 
-	username     string
-	accessToken  string
-	password     string
+	// mtx guards the tokens below, which are swapped out whenever the API
+	// rejects the current access token.
+	mtx          sync.RWMutex
 	token        string
 	refreshToken string
+
+	teams   *teamCache
+	users   *userCache
+	members *memberCache
+}
+
+type clientOptions struct {
+	baseUrl        *url.URL
+	loginUrl       *url.URL
+	tlsConfig      *tls.Config
+	teamCacheTTL   time.Duration
+	userCacheTTL   time.Duration
+	memberCacheTTL time.Duration
+}
+
+// Option customizes the client created by NewClient.
+type Option func(*clientOptions)
+
+// WithBaseURL points the client at a different DockerHub API, e.g. a mock of it.
+func WithBaseURL(baseUrl *url.URL) Option {
+	return func(o *clientOptions) {
+		o.baseUrl = baseUrl
+	}
+}
+
+// WithLoginURL sends the authentication requests to a different host than the
+// rest of the API. By default, the base URL is used for both.
+func WithLoginURL(loginUrl *url.URL) Option {
+	return func(o *clientOptions) {
+		o.loginUrl = loginUrl
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the underlying HTTP client, e.g. to
+// trust the CA of a TLS-intercepting proxy.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithTeamCacheTTL sets how long the team IDs resolved by ResolveTeamID are remembered.
+func WithTeamCacheTTL(ttl time.Duration) Option {
+	return func(o *clientOptions) {
+		o.teamCacheTTL = ttl
+	}
 }
 
-type CredentialsReq struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+// WithUserCacheTTL sets how long the users looked up by LookupUser are remembered.
+func WithUserCacheTTL(ttl time.Duration) Option {
+	return func(o *clientOptions) {
+		o.userCacheTTL = ttl
+	}
 }
 
-type TokenResp struct {
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
+// WithMemberCacheTTL sets how long the members looked up by IsOrganizationMember are remembered.
+func WithMemberCacheTTL(ttl time.Duration) Option {
+	return func(o *clientOptions) {
+		o.memberCacheTTL = ttl
+	}
 }
 
-func NewClient(ctx context.Context, username, password, accessToken string) (*Client, error) {
-	base := &url.URL{
-		Scheme: "https",
-		Host:   BaseDomain,
+func NewClient(ctx context.Context, auth Authenticator, opts ...Option) (*Client, error) {
+	options := clientOptions{
+		baseUrl: &url.URL{
+			Scheme: "https",
+			Host:   BaseDomain,
+		},
+		teamCacheTTL:   DefaultTeamCacheTTL,
+		userCacheTTL:   DefaultUserCacheTTL,
+		memberCacheTTL: DefaultMemberCacheTTL,
+	}
+
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	if options.loginUrl == nil {
+		options.loginUrl = options.baseUrl
+	}
+
+	httpOptions := []uhttp.Option{uhttp.WithLogger(true, ctxzap.Extract(ctx))}
+	if options.tlsConfig != nil {
+		httpOptions = append(httpOptions, uhttp.WithTLSClientConfig(options.tlsConfig))
+	}
+
+	httpClient, err := uhttp.NewClient(ctx, httpOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,56 +164,128 @@ func NewClient(ctx context.Context, username, password, accessToken string) (*Cl
 		return nil, err
 	}
 
-	// Use username + password/accesstoken to get an auth token and refresh token
-	credentials := CredentialsReq{
-		Username: username,
-		Password: password,
+	client := &Client{
+		httpClient: wrapper,
+		baseUrl:    options.baseUrl,
+		loginUrl:   options.loginUrl,
+		auth:       auth,
+		teams:      newTeamCache(options.teamCacheTTL),
+		users:      newUserCache(options.userCacheTTL),
+		members:    newMemberCache(options.memberCacheTTL),
+	}
+
+	data, err := client.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client.setTokens(data)
+
+	return client, nil
+}
+
+func (c *Client) authenticate(ctx context.Context) (*TokenResp, error) {
+	data, err := c.auth.Authenticate(ctx, c)
+	if err != nil {
+		return nil, err
 	}
-	if password == "" {
-		credentials.Password = accessToken
+
+	if data.Token == "" {
+		return nil, fmt.Errorf("dockerhub-connector: authentication did not return an access token")
 	}
 
+	return data, nil
+}
+
+// requestToken posts the body to one of the token issuing endpoints.
+func (c *Client) requestToken(ctx context.Context, endpoint string, body interface{}, response interface{}) error {
 	reqOptions := []uhttp.RequestOption{
 		uhttp.WithContentType("application/json"),
 		uhttp.WithAccept("application/json"),
-		uhttp.WithJSONBody(credentials),
+		uhttp.WithJSONBody(body),
+	}
+
+	// POST keeps the token responses out of the GET response cache of uhttp,
+	// otherwise a re-login could be served the very token that just expired.
+	req, err := c.httpClient.NewRequest(ctx, http.MethodPost, composeURL(c.loginUrl, endpoint), reqOptions...)
+	if err != nil {
+		return err
 	}
 
-	urlAddress := base.ResolveReference(&url.URL{Path: LoginEndpoint})
+	resp, err := c.httpClient.Do(req, uhttp.WithJSONResponse(response))
+	if resp != nil {
+		defer resp.Body.Close()
+	}
 
-	req, err := wrapper.NewRequest(ctx, http.MethodGet, urlAddress, reqOptions...)
 	if err != nil {
-		return nil, err
+		return newAPIError(req, resp, err)
 	}
 
-	data := &TokenResp{}
-	doOptions := []uhttp.DoOption{
-		uhttp.WithJSONResponse(data),
+	return nil
+}
+
+func (c *Client) setTokens(data *TokenResp) {
+	c.token = data.Token
+	if data.RefreshToken != "" {
+		c.refreshToken = data.RefreshToken
+	}
+}
+
+func (c *Client) currentToken() string {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.token
+}
+
+// reauthenticate replaces an access token rejected by the API. The refresh token
+// is tried first when the authenticator supports it, falling back to a full
+// authentication with the configured credentials, as refreshing relies on
+// undocumented DockerHub behavior (see refresh).
+// Concurrent callers that were rejected with the same stale token share a single
+// renewal: once the token has changed, there is nothing left to do.
+func (c *Client) reauthenticate(ctx context.Context, staleToken string) error {
+	l := ctxzap.Extract(ctx)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.token != staleToken {
+		return nil
+	}
+
+	if refresher, ok := c.auth.(Refresher); ok && c.refreshToken != "" {
+		data, err := refresher.Refresh(ctx, c, c.refreshToken)
+		if err == nil && data.Token != "" {
+			c.setTokens(data)
+			return nil
+		}
+
+		l.Debug("dockerhub-connector: failed to refresh access token, authenticating again", zap.Error(err))
 	}
-	resp, err := wrapper.Do(req, doOptions...)
+
+	data, err := c.authenticate(ctx)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("dockerhub-connector: failed to renew access token: %w", err)
 	}
 
-	defer resp.Body.Close()
+	if data.Token == staleToken {
+		return fmt.Errorf("dockerhub-connector: access token was rejected and can't be renewed")
+	}
 
-	return &Client{
-		httpClient:   wrapper,
-		baseUrl:      base,
-		username:     username,
-		accessToken:  accessToken,
-		password:     password,
-		token:        data.Token,
-		refreshToken: data.RefreshToken,
-	}, nil
+	c.setTokens(data)
+
+	return nil
 }
 
 func (c *Client) composeURL(endpoint string, params ...interface{}) *url.URL {
-	return c.baseUrl.ResolveReference(
-		&url.URL{
-			Path: fmt.Sprintf(endpoint, params...),
-		},
-	)
+	return composeURL(c.baseUrl, endpoint, params...)
+}
+
+// composeURL appends the endpoint to the path of the base, so that APIs served
+// under a path prefix (e.g. a mock behind a gateway) work too.
+func composeURL(base *url.URL, endpoint string, params ...interface{}) *url.URL {
+	return base.JoinPath(fmt.Sprintf(endpoint, params...))
 }
 
 type PaginationVars struct {
@@ -139,68 +305,310 @@ func (c *Client) SetCurrentUser(ctx context.Context, username string) error {
 	return nil
 }
 
-// ListOrganizations return organizations for the current user.
-func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) ([]Organization, string, error) {
-	var response ListResponse[Organization]
+// ScopedOrganization returns the only organization the credentials grant access
+// to, or an empty string when they belong to a user account.
+func (c *Client) ScopedOrganization() string {
+	if scoped, ok := c.auth.(OrganizationScoped); ok {
+		return scoped.ScopedOrganization()
+	}
+
+	return ""
+}
+
+// GetCurrentUser return the user the client is authenticated as.
+func (c *Client) GetCurrentUser(ctx context.Context) (*User, *v2.RateLimitDescription, error) {
+	var response User
 
-	err := c.doRequest(
+	rateLimitData, err := c.doRequest(
 		ctx,
 		http.MethodGet,
-		c.composeURL(UserOrgsEndpoint, c.currentUser),
+		c.composeURL(CurrentUserEndpoint),
 		&response,
 		nil,
 		nil,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, rateLimitData, err
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	return &response, rateLimitData, nil
 }
 
-// ListUsers return users under the provided organization.
-func (c *Client) ListUsers(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]User, string, error) {
-	var response ListResponse[User]
+// ListAccessTokens returns the personal access tokens of the current user. DockerHub doesn't allow
+// sessions started with a personal access token to list them.
+func (c *Client) ListAccessTokens(ctx context.Context, pVars *PaginationVars) ([]AccessToken, string, *v2.RateLimitDescription, error) {
+	return listPage[AccessToken](ctx, c, pVars, AccessTokensEndpoint)
+}
+
+// GetUser returns the public profile of the user.
+func (c *Client) GetUser(ctx context.Context, username string) (*User, *v2.RateLimitDescription, error) {
+	var response User
 
-	err := c.doRequest(
+	rateLimitData, err := c.doRequest(
 		ctx,
 		http.MethodGet,
-		c.composeURL(UsersEndpoint, orgSlug),
+		c.composeURL(UserEndpoint, username),
 		&response,
 		nil,
-		pVars,
+		nil,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, rateLimitData, err
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	return &response, rateLimitData, nil
 }
 
-// ListTeams return teams under the provided organization.
-func (c *Client) ListTeams(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]Team, string, error) {
-	var response ListResponse[Team]
+// ListAuditLogs returns a page of the audit log of the organization, starting from the given time.
+// The audit log doesn't link to the next page, there are more pages as long as pages are full.
+func (c *Client) ListAuditLogs(ctx context.Context, orgSlug string, from time.Time, pVars *PaginationVars) ([]AuditLog, *v2.RateLimitDescription, error) {
+	var response AuditLogsResp
 
-	err := c.doRequest(
+	urlAddress := c.composeURL(AuditLogsEndpoint, orgSlug)
+	if !from.IsZero() {
+		q := urlAddress.Query()
+		q.Set("from", from.UTC().Format(time.RFC3339Nano))
+		urlAddress.RawQuery = q.Encode()
+	}
+
+	rateLimitData, err := c.doRequest(
 		ctx,
 		http.MethodGet,
-		c.composeURL(TeamsEndpoint, orgSlug),
+		urlAddress,
 		&response,
 		nil,
 		pVars,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, rateLimitData, err
+	}
+
+	return response.Logs, rateLimitData, nil
+}
+
+// GetOrganization return organization details.
+func (c *Client) GetOrganization(ctx context.Context, orgSlug string) (*Organization, *v2.RateLimitDescription, error) {
+	var response Organization
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		c.composeURL(OrgDetailEndpoint, orgSlug),
+		&response,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	return &response, rateLimitData, nil
+}
+
+// ListOrganizations return organizations for the current user.
+// With organization scoped credentials, only the organization in scope is returned.
+func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) ([]Organization, string, *v2.RateLimitDescription, error) {
+	if orgSlug := c.ScopedOrganization(); orgSlug != "" {
+		org, rateLimitData, err := c.GetOrganization(ctx, orgSlug)
+		if err != nil {
+			return nil, "", rateLimitData, err
+		}
+
+		return []Organization{*org}, "", rateLimitData, nil
+	}
+
+	return listPage[Organization](ctx, c, pVars, UserOrgsEndpoint, c.currentUser)
+}
+
+// ListUsers return users under the provided organization.
+func (c *Client) ListUsers(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]User, string, *v2.RateLimitDescription, error) {
+	return listPage[User](ctx, c, pVars, UsersEndpoint, orgSlug)
+}
+
+// GetOrganizationMember returns the member of the organization with the given username,
+// or nil when the user is not a member of it.
+func (c *Client) GetOrganizationMember(ctx context.Context, orgSlug, username string) (*User, *v2.RateLimitDescription, error) {
+	paginator := NewPaginator[User](c, &PaginationVars{Size: 100}, UsersEndpoint, orgSlug)
+	for member, err := range paginator.All(ctx) {
+		if err != nil {
+			return nil, paginator.RateLimit(), err
+		}
+
+		if strings.EqualFold(member.Username, username) {
+			return &member, paginator.RateLimit(), nil
+		}
+	}
+
+	return nil, paginator.RateLimit(), nil
+}
+
+// FindOrganizationMember returns the member of the organization whose username or email is any of the given
+// ones, or nil when none of them belongs to a member of it.
+func (c *Client) FindOrganizationMember(ctx context.Context, orgSlug string, usernamesOrEmails []string) (*User, *v2.RateLimitDescription, error) {
+	paginator := NewPaginator[User](c, &PaginationVars{Size: 100}, UsersEndpoint, orgSlug)
+	for member, err := range paginator.All(ctx) {
+		if err != nil {
+			return nil, paginator.RateLimit(), err
+		}
+
+		for _, v := range usernamesOrEmails {
+			if strings.EqualFold(member.Username, v) || (member.Email != "" && strings.EqualFold(member.Email, v)) {
+				return &member, paginator.RateLimit(), nil
+			}
+		}
+	}
+
+	return nil, paginator.RateLimit(), nil
+}
+
+// UpdateOrganizationMemberRole changes the role of an existing member of the organization.
+func (c *Client) UpdateOrganizationMemberRole(ctx context.Context, orgSlug, username, role string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodPut,
+		c.composeURL(OrgMemberEndpoint, orgSlug, username),
+		nil,
+		UpdateMemberRoleReq{Role: role},
+		nil,
+	)
+}
+
+// RemoveOrganizationMember removes the user from the organization and all of its teams.
+func (c *Client) RemoveOrganizationMember(ctx context.Context, orgSlug, username string) (*v2.RateLimitDescription, error) {
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(OrgMemberEndpoint, orgSlug, username),
+		nil,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return rateLimitData, err
+	}
+
+	c.members.invalidate(orgSlug)
+
+	return rateLimitData, nil
+}
+
+// InviteToOrganization invites the users, by username or email, to join the organization with the given role.
+// With a team, the users also join the team once they accept the invitation.
+func (c *Client) InviteToOrganization(ctx context.Context, orgSlug, teamSlug, role string, invitees ...string) ([]InviteeResult, *v2.RateLimitDescription, error) {
+	var response BulkInviteResp
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		c.composeURL(BulkInvitesEndpoint),
+		&response,
+		BulkInviteReq{Org: orgSlug, Team: teamSlug, Role: role, Invitees: invitees},
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	return response.Invitees, rateLimitData, nil
+}
+
+// ListInvites returns the pending invitations to the organization. DockerHub returns them all at once.
+func (c *Client) ListInvites(ctx context.Context, orgSlug string) ([]Invite, *v2.RateLimitDescription, error) {
+	var response InvitesResp
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		c.composeURL(OrgInvitesEndpoint, orgSlug),
+		&response,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	return response.Data, rateLimitData, nil
+}
+
+// DeleteInvite cancels a pending invitation.
+func (c *Client) DeleteInvite(ctx context.Context, inviteId string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(InviteEndpoint, inviteId),
+		nil,
+		nil,
+		nil,
+	)
+}
+
+// ListOrgAccessTokens returns the access tokens of the organization, without the resources they can access.
+func (c *Client) ListOrgAccessTokens(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]OrgAccessToken, string, *v2.RateLimitDescription, error) {
+	return listPage[OrgAccessToken](ctx, c, pVars, OrgAccessTokensEndpoint, orgSlug)
+}
+
+// GetOrgAccessToken returns the access token of the organization together with the resources it can access.
+func (c *Client) GetOrgAccessToken(ctx context.Context, orgSlug, tokenId string) (*OrgAccessToken, *v2.RateLimitDescription, error) {
+	var response OrgAccessToken
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		c.composeURL(OrgAccessTokenEndpoint, orgSlug, tokenId),
+		&response,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	return &response, rateLimitData, nil
+}
+
+// CreateOrgAccessToken creates an access token of the organization. The response is the only
+// place the secret of the token is ever returned.
+func (c *Client) CreateOrgAccessToken(ctx context.Context, orgSlug string, req CreateOrgAccessTokenReq) (*OrgAccessToken, *v2.RateLimitDescription, error) {
+	var response OrgAccessToken
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodPost,
+		c.composeURL(OrgAccessTokensEndpoint, orgSlug),
+		&response,
+		req,
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	return &response, rateLimitData, nil
+}
+
+// DeleteOrgAccessToken deletes the access token of the organization, it can't be used anymore afterwards.
+func (c *Client) DeleteOrgAccessToken(ctx context.Context, orgSlug, tokenId string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(OrgAccessTokenEndpoint, orgSlug, tokenId),
+		nil,
+		nil,
+		nil,
+	)
+}
+
+// ListTeams return teams under the provided organization.
+func (c *Client) ListTeams(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]Team, string, *v2.RateLimitDescription, error) {
+	return listPage[Team](ctx, c, pVars, TeamsEndpoint, orgSlug)
 }
 
 // GetTeam return team details.
-func (c *Client) GetTeam(ctx context.Context, orgSlug, teamId string) (*Team, error) {
+func (c *Client) GetTeam(ctx context.Context, orgSlug, teamId string) (*Team, *v2.RateLimitDescription, error) {
 	var response Team
 
-	err := c.doRequest(
+	rateLimitData, err := c.doRequest(
 		ctx,
 		http.MethodGet,
 		c.composeURL(TeamDetailEndpoint, orgSlug, teamId),
@@ -209,67 +617,197 @@ func (c *Client) GetTeam(ctx context.Context, orgSlug, teamId string) (*Team, er
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return nil, rateLimitData, err
 	}
 
-	return &response, nil
+	return &response, rateLimitData, nil
 }
 
-// ListTeamMembers return team members.
-func (c *Client) ListTeamMembers(ctx context.Context, orgSlug, teamSlug string, pVars *PaginationVars) ([]User, string, error) {
-	var response ListResponse[User]
+// CreateTeam creates a new team in the organization.
+func (c *Client) CreateTeam(ctx context.Context, orgSlug, name, description string) (*Team, *v2.RateLimitDescription, error) {
+	var response Team
 
-	err := c.doRequest(
+	rateLimitData, err := c.doRequest(
 		ctx,
-		http.MethodGet,
-		c.composeURL(TeamMembersEndpoint, orgSlug, teamSlug),
+		http.MethodPost,
+		c.composeURL(TeamsEndpoint, orgSlug),
 		&response,
+		CreateTeamReq{Name: name, Description: description},
+		nil,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	c.teams.invalidate(orgSlug)
+
+	return &response, rateLimitData, nil
+}
+
+// DeleteTeam deletes the team, its members stay members of the organization.
+func (c *Client) DeleteTeam(ctx context.Context, orgSlug, teamSlug string) (*v2.RateLimitDescription, error) {
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(TeamDetailEndpoint, orgSlug, teamSlug),
+		nil,
+		nil,
 		nil,
-		pVars,
 	)
 	if err != nil {
-		return nil, "", err
+		return rateLimitData, err
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	c.teams.invalidate(orgSlug)
+
+	return rateLimitData, nil
 }
 
-// ListRepositories return repositories under the provided organization.
-func (c *Client) ListRepositories(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]Repository, string, error) {
-	var response ListResponse[Repository]
+// ListTeamMembers return team members.
+func (c *Client) ListTeamMembers(ctx context.Context, orgSlug, teamSlug string, pVars *PaginationVars) ([]User, string, *v2.RateLimitDescription, error) {
+	return listPage[User](ctx, c, pVars, TeamMembersEndpoint, orgSlug, teamSlug)
+}
 
-	err := c.doRequest(
+// AddTeamMember adds a member of the organization to the team.
+func (c *Client) AddTeamMember(ctx context.Context, orgSlug, teamSlug, username string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodPost,
+		c.composeURL(TeamMembersEndpoint, orgSlug, teamSlug),
+		nil,
+		AddTeamMemberReq{Member: username},
+		nil,
+	)
+}
+
+// RemoveTeamMember removes the user from the team, the user stays a member of the organization.
+func (c *Client) RemoveTeamMember(ctx context.Context, orgSlug, teamSlug, username string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(TeamMemberEndpoint, orgSlug, teamSlug, username),
+		nil,
+		nil,
+		nil,
+	)
+}
+
+// ListRepositories return repositories under the provided organization or personal namespace.
+func (c *Client) ListRepositories(ctx context.Context, orgSlug string, pVars *PaginationVars) ([]Repository, string, *v2.RateLimitDescription, error) {
+	return listPage[Repository](ctx, c, pVars, RepositoriesEndpoint, orgSlug)
+}
+
+// ListRepositoryCollaborators returns the users with access to a repository of a personal namespace.
+func (c *Client) ListRepositoryCollaborators(ctx context.Context, namespace, repoSlug string, pVars *PaginationVars) ([]Collaborator, string, *v2.RateLimitDescription, error) {
+	return listPage[Collaborator](ctx, c, pVars, CollaboratorsEndpoint, namespace, repoSlug)
+}
+
+// GetRepository returns the repository of the namespace.
+func (c *Client) GetRepository(ctx context.Context, namespace, repoSlug string) (*Repository, *v2.RateLimitDescription, error) {
+	var response Repository
+
+	rateLimitData, err := c.doRequest(
 		ctx,
 		http.MethodGet,
-		c.composeURL(RepositoriesEndpoint, orgSlug),
+		c.composeURL(RepositoryEndpoint, namespace, repoSlug),
 		&response,
 		nil,
-		pVars,
+		nil,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, rateLimitData, err
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	return &response, rateLimitData, nil
 }
 
-// ListTeamPermissions return team permissions on provided repository.
-func (c *Client) ListRepositoryPermissions(ctx context.Context, orgSlug, repoSlug string, pVars *PaginationVars) ([]RepositoryPermission, string, error) {
-	var response ListResponse[RepositoryPermission]
+// CreateRepository creates a new repository in the namespace.
+func (c *Client) CreateRepository(ctx context.Context, namespace, name, description string, private bool) (*Repository, *v2.RateLimitDescription, error) {
+	var response Repository
 
-	err := c.doRequest(
+	rateLimitData, err := c.doRequest(
 		ctx,
-		http.MethodGet,
-		c.composeURL(RepositoryPermissions, orgSlug, repoSlug),
+		http.MethodPost,
+		c.composeURL(CreateRepositoryEndpoint),
 		&response,
+		CreateRepositoryReq{Namespace: namespace, Name: name, Description: description, IsPrivate: private},
 		nil,
-		pVars,
 	)
 	if err != nil {
-		return nil, "", err
+		return nil, rateLimitData, err
+	}
+
+	return &response, rateLimitData, nil
+}
+
+// DeleteRepository deletes the repository together with all of its images.
+func (c *Client) DeleteRepository(ctx context.Context, namespace, repoSlug string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(RepositoryEndpoint, namespace, repoSlug),
+		nil,
+		nil,
+		nil,
+	)
+}
+
+// ListTeamPermissions return team permissions on provided repository.
+func (c *Client) ListRepositoryPermissions(ctx context.Context, orgSlug, repoSlug string, pVars *PaginationVars) ([]RepositoryPermission, string, *v2.RateLimitDescription, error) {
+	return listPage[RepositoryPermission](ctx, c, pVars, RepositoryPermissions, orgSlug, repoSlug)
+}
+
+// GetRepositoryPermission returns the permission of the team on the repository,
+// or nil when the team has no access to it.
+func (c *Client) GetRepositoryPermission(ctx context.Context, orgSlug, repoSlug string, teamId int) (*RepositoryPermission, *v2.RateLimitDescription, error) {
+	paginator := NewPaginator[RepositoryPermission](c, &PaginationVars{Size: 100}, RepositoryPermissions, orgSlug, repoSlug)
+	for perm, err := range paginator.All(ctx) {
+		if err != nil {
+			return nil, paginator.RateLimit(), err
+		}
+
+		if perm.TeamId == teamId {
+			return &perm, paginator.RateLimit(), nil
+		}
 	}
 
-	return response.Results, parsePageFromURL(response.Next), nil
+	return nil, paginator.RateLimit(), nil
+}
+
+// AddRepositoryPermission gives a team without access to the repository the permission on it.
+func (c *Client) AddRepositoryPermission(ctx context.Context, orgSlug, repoSlug string, teamId int, permission string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodPost,
+		c.composeURL(RepositoryPermissions, orgSlug, repoSlug),
+		nil,
+		RepositoryPermissionReq{TeamId: teamId, Permission: permission},
+		nil,
+	)
+}
+
+// UpdateRepositoryPermission changes the permission of a team that already has access to the repository.
+func (c *Client) UpdateRepositoryPermission(ctx context.Context, orgSlug, repoSlug string, teamId int, permission string) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodPatch,
+		c.composeURL(RepositoryTeamPermission, orgSlug, repoSlug, teamId),
+		nil,
+		RepositoryPermissionReq{Permission: permission},
+		nil,
+	)
+}
+
+// RemoveRepositoryPermission takes away the access of the team to the repository.
+func (c *Client) RemoveRepositoryPermission(ctx context.Context, orgSlug, repoSlug string, teamId int) (*v2.RateLimitDescription, error) {
+	return c.doRequest(
+		ctx,
+		http.MethodDelete,
+		c.composeURL(RepositoryTeamPermission, orgSlug, repoSlug, teamId),
+		nil,
+		nil,
+		nil,
+	)
 }
 
 func setupPagination(ctx context.Context, addr *url.URL, paginationVars *PaginationVars) *url.Values {
@@ -299,42 +837,109 @@ func (c *Client) doRequest(
 	response interface{},
 	data interface{},
 	paginationVars *PaginationVars,
-) error {
-	var err error
+) (*v2.RateLimitDescription, error) {
+	l := ctxzap.Extract(ctx)
 
+	q := setupPagination(ctx, urlAddress, paginationVars)
+	if q != nil {
+		urlAddress.RawQuery = q.Encode()
+	}
+
+	token := c.currentToken()
+	renewed := false
+	retries := 0
+
+	for {
+		rateLimitData := &v2.RateLimitDescription{}
+
+		resp, err := c.send(ctx, method, urlAddress, response, data, token, rateLimitData)
+		if err == nil && method != http.MethodGet {
+			// uhttp caches GET responses, drop them so that the change is visible to subsequent reads
+			if err := uhttp.ClearCaches(ctx); err != nil {
+				l.Warn("dockerhub-connector: failed to clear http cache", zap.Error(err))
+			}
+		}
+
+		if err == nil || resp == nil {
+			return rateLimitData, err
+		}
+
+		switch {
+		// retry once with a renewed token when the current one expired or was revoked
+		case resp.StatusCode == http.StatusUnauthorized && !renewed:
+			l.Debug(
+				"dockerhub-connector: access token rejected, renewing it",
+				zap.String("url", urlAddress.String()),
+			)
+
+			if err := c.reauthenticate(ctx, token); err != nil {
+				return rateLimitData, err
+			}
+
+			renewed = true
+			token = c.currentToken()
+
+		case resp.StatusCode == http.StatusTooManyRequests && retries < maxRateLimitRetries:
+			delay := retryDelay(resp, retries, time.Now())
+			l.Debug(
+				"dockerhub-connector: rate limited, waiting before retrying",
+				zap.String("url", urlAddress.String()),
+				zap.Duration("delay", delay),
+			)
+
+			if err := sleep(ctx, delay); err != nil {
+				return rateLimitData, err
+			}
+
+			retries++
+
+		default:
+			return rateLimitData, err
+		}
+	}
+}
+
+func (c *Client) send(
+	ctx context.Context,
+	method string,
+	urlAddress *url.URL,
+	response interface{},
+	data interface{},
+	token string,
+	rateLimitData *v2.RateLimitDescription,
+) (*http.Response, error) {
 	reqOptions := []uhttp.RequestOption{
 		uhttp.WithContentType("application/json"),
 		uhttp.WithAccept("application/json"),
-		uhttp.WithBearerToken(c.token),
+		uhttp.WithBearerToken(token),
 	}
 
 	if data != nil {
 		reqOptions = append(reqOptions, uhttp.WithJSONBody(data))
 	}
 
-	q := setupPagination(ctx, urlAddress, paginationVars)
-	if q != nil {
-		urlAddress.RawQuery = q.Encode()
-	}
-
 	req, err := c.httpClient.NewRequest(ctx, method, urlAddress, reqOptions...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	doOptions := []uhttp.DoOption{}
+	doOptions := []uhttp.DoOption{
+		uhttp.WithRatelimitData(rateLimitData),
+	}
 	if response != nil {
 		doOptions = append(doOptions, uhttp.WithJSONResponse(response))
 	}
 
 	resp, err := c.httpClient.Do(req, doOptions...)
-	if err != nil {
-		return err
+	if resp != nil {
+		defer resp.Body.Close()
 	}
 
-	defer resp.Body.Close()
+	if err != nil {
+		return resp, newAPIError(req, resp, err)
+	}
 
-	return nil
+	return resp, nil
 }
 
 func parsePageFromURL(urlPayload string) string {
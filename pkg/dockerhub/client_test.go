@@ -0,0 +1,246 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newOrgsServer serves a login endpoint and the organizations of jdoe, paginated
+// by the page and page_size query parameters like DockerHub does.
+func newOrgsServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"token"}`))
+	})
+
+	var server *httptest.Server
+	mux.HandleFunc("GET "+fmt.Sprintf(UserOrgsEndpoint, "jdoe"), func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			page = 1
+		}
+		size, err := strconv.Atoi(r.URL.Query().Get("page_size"))
+		if err != nil {
+			size = 10
+		}
+
+		response := ListResponse[Organization]{}
+		response.Count = total
+		for i := (page - 1) * size; i < min(page*size, total); i++ {
+			response.Results = append(response.Results, Organization{
+				BaseResource: BaseResource{Id: strconv.Itoa(i)},
+				Name:         fmt.Sprintf("org-%d", i),
+			})
+		}
+
+		if page*size < total {
+			next, _ := url.Parse(server.URL + r.URL.Path)
+			next.RawQuery = url.Values{"page": {strconv.Itoa(page + 1)}, "page_size": {strconv.Itoa(size)}}.Encode()
+			response.Next = next.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestClient(ctx context.Context, t *testing.T, server *httptest.Server) *Client {
+	t.Helper()
+
+	baseUrl, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(ctx, &PasswordAuth{Username: "jdoe", Password: "secret"}, WithBaseURL(baseUrl))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.SetCurrentUser(ctx, "jdoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func TestListOrganizationsPagination(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(ctx, t, newOrgsServer(t, 7))
+
+	seen := map[string]int{}
+	page := ""
+	for i := 0; ; i++ {
+		if i > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		orgs, next, _, err := client.ListOrganizations(ctx, &PaginationVars{Size: 3, Page: page})
+		if err != nil {
+			t.Fatalf("failed to list organizations: %v", err)
+		}
+
+		if len(orgs) > 3 {
+			t.Fatalf("expected at most 3 organizations per page, got %d", len(orgs))
+		}
+
+		for _, org := range orgs {
+			seen[org.Name]++
+		}
+
+		if next == "" {
+			break
+		}
+		page = next
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 organizations, got %d", len(seen))
+	}
+	for name, count := range seen {
+		if count != 1 {
+			t.Errorf("organization %s listed %d times", name, count)
+		}
+	}
+}
+
+// tokenServer issues numbered access tokens on login, accepting only the latest one,
+// and counts the logins with credentials and with a refresh token.
+type tokenServer struct {
+	mtx           sync.Mutex
+	issued        int
+	valid         string
+	logins        int
+	refreshes     int
+	rejectRefresh bool
+}
+
+func (s *tokenServer) expire() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.valid = ""
+}
+
+func (s *tokenServer) counts() (int, int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.logins, s.refreshes
+}
+
+func newTokenServer(t *testing.T, ts *tokenServer) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ts.mtx.Lock()
+		defer ts.mtx.Unlock()
+
+		if _, ok := req["refresh_token"]; ok {
+			ts.refreshes++
+			if ts.rejectRefresh || req["refresh_token"] != "refresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else {
+			ts.logins++
+		}
+
+		ts.issued++
+		ts.valid = fmt.Sprintf("token-%d", ts.issued)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(TokenResp{Token: ts.valid, RefreshToken: "refresh-token"})
+	})
+	mux.HandleFunc("GET "+CurrentUserEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		ts.mtx.Lock()
+		valid := ts.valid
+		ts.mtx.Unlock()
+
+		if valid == "" || r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","username":"jdoe"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestReauthenticateOncePerStaleToken(t *testing.T) {
+	ctx := context.Background()
+	ts := &tokenServer{}
+	client := newTestClient(ctx, t, newTokenServer(t, ts))
+
+	ts.expire()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _, err := client.GetCurrentUser(ctx)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected requests to succeed with the renewed token: %v", err)
+		}
+	}
+
+	logins, refreshes := ts.counts()
+	if logins != 1 || refreshes != 1 {
+		t.Errorf("expected the initial login and a single refresh, got %d logins and %d refreshes", logins, refreshes)
+	}
+}
+
+func TestReauthenticateFallsBackToLogin(t *testing.T) {
+	ctx := context.Background()
+	ts := &tokenServer{rejectRefresh: true}
+	client := newTestClient(ctx, t, newTokenServer(t, ts))
+
+	ts.expire()
+
+	if _, _, err := client.GetCurrentUser(ctx); err != nil {
+		t.Fatalf("expected the request to succeed after logging in again: %v", err)
+	}
+
+	logins, refreshes := ts.counts()
+	if logins != 2 || refreshes != 1 {
+		t.Errorf("expected a rejected refresh followed by a login, got %d logins and %d refreshes", logins, refreshes)
+	}
+}
@@ -0,0 +1,1751 @@
+// Package dockerhubtest provides an in-memory DockerHub API for tests.
+//
+// The server implements the subset of the DockerHub API used by the connector:
+// login, organizations, members, groups (teams), group members, repositories,
+// repository groups, repository collaborators and invites. It is seeded from a Fixture, paginates list
+// endpoints like DockerHub does and can be told to fail requests on demand.
+package dockerhubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const defaultPageSize = 10
+
+const (
+	roleOwner  = "owner"
+	roleEditor = "editor"
+	roleMember = "member"
+)
+
+// Fixture is the initial state of the server.
+type Fixture struct {
+	Users         []User
+	Organizations []Organization
+}
+
+// User is a DockerHub account. Password is accepted by the login endpoint,
+// as are any of the personal access tokens. Repositories are in the personal
+// namespace of the user, which only the user has access to.
+type User struct {
+	Username     string
+	FullName     string
+	Email        string
+	Password     string
+	AccessTokens []AccessToken
+	Repositories []Repository
+}
+
+// AccessToken is a personal access token. Inactive or expired tokens are rejected
+// by the login endpoint. Sessions started with an access token can't list access tokens.
+type AccessToken struct {
+	Token      string
+	Label      string
+	Scopes     []string
+	Inactive   bool
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Organization is a DockerHub organization. AccessTokens are the organization
+// access tokens that can be exchanged for an access token scoped to it.
+type Organization struct {
+	Name         string
+	Members      []Member
+	Teams        []Team
+	Repositories []Repository
+	Invites      []Invite
+	AccessTokens []OrgAccessToken
+	AuditLogs    []AuditLog
+}
+
+// AuditLog is an entry of the audit log of an organization. Name is the object of the action,
+// e.g. acme/developers for team actions, Data holds details like the member added to the team.
+type AuditLog struct {
+	Action    string
+	Name      string
+	Actor     string
+	Data      map[string]string
+	Timestamp time.Time
+}
+
+// OrgAccessToken is an organization access token. Repositories maps repository paths,
+// e.g. acme/api, to the scopes the token has on them, e.g. repo-pull or repo-push.
+// Inactive tokens can't be exchanged for access tokens.
+type OrgAccessToken struct {
+	Token        string
+	Label        string
+	Description  string
+	CreatedBy    string
+	Inactive     bool
+	ExpiresAt    time.Time
+	LastUsedAt   time.Time
+	Repositories map[string][]string
+}
+
+// Member assigns a user one of the owner, editor or member roles.
+type Member struct {
+	Username string
+	Role     string
+}
+
+// Team is a group of organization members.
+type Team struct {
+	Name        string
+	Description string
+	Members     []string
+}
+
+// Repository is a repository in the namespace of the organization or user. Teams maps
+// the names of the teams with access to their permission (read, write or admin),
+// Collaborators the usernames of users with access to a personal repository.
+type Repository struct {
+	Name          string
+	Description   string
+	Private       bool
+	Teams         map[string]string
+	Collaborators map[string]string
+}
+
+// Invite is a pending invitation of a username or email to the organization,
+// and optionally one of its teams.
+type Invite struct {
+	Invitee string
+	Role    string
+	Team    string
+}
+
+// Failure makes the server reject matching requests. An empty Method matches
+// any method; Path is compared with the request path as is.
+type Failure struct {
+	Method string
+	Path   string
+	Status int
+	Header http.Header
+	Body   string
+	// Times is how many requests fail before the failure is cleared, zero or less
+	// means every matching request fails.
+	Times int
+}
+
+type user struct {
+	id           string
+	accessTokens []*accessToken
+	// namespace holds the personal repositories, the user is its only member
+	namespace *organization
+	User
+}
+
+type accessToken struct {
+	id      string
+	created time.Time
+	AccessToken
+}
+
+type team struct {
+	id          int
+	name        string
+	description string
+	members     []string
+}
+
+type repository struct {
+	name        string
+	description string
+	private     bool
+	// teams maps team IDs to their permission
+	teams map[int]string
+	// collaborators maps usernames to their permission
+	collaborators map[string]string
+}
+
+type invite struct {
+	id      string
+	inviter string
+	invitee string
+	role    string
+	team    string
+	created time.Time
+}
+
+type orgAccessToken struct {
+	id           string
+	token        string
+	label        string
+	description  string
+	createdBy    string
+	active       bool
+	created      time.Time
+	expires      time.Time
+	lastUsed     time.Time
+	repositories map[string][]string
+}
+
+type organization struct {
+	id           string
+	name         string
+	members      map[string]string
+	teams        []*team
+	repositories []*repository
+	invites      []*invite
+	accessTokens []*orgAccessToken
+	auditLogs    []AuditLog
+}
+
+// principal is who an access token was issued to, either a user or an
+// organization for organization access tokens. pat tells whether the user
+// logged in with a personal access token.
+type principal struct {
+	username string
+	org      string
+	pat      bool
+}
+
+// Server is an in-memory DockerHub API backed by an httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mtx           sync.Mutex
+	ids           int
+	users         map[string]*user
+	orgs          map[string]*organization
+	tokens        map[string]principal
+	refreshTokens map[string]principal
+	failures      []*Failure
+	requests      []string
+}
+
+// NewServer starts a server seeded with the fixture. It is closed when the test ends.
+func NewServer(t testing.TB, fixture Fixture) *Server {
+	t.Helper()
+
+	s := &Server{
+		users:         map[string]*user{},
+		orgs:          map[string]*organization{},
+		tokens:        map[string]principal{},
+		refreshTokens: map[string]principal{},
+	}
+
+	for _, u := range fixture.Users {
+		usr := &user{id: s.nextID(), User: u}
+		for _, t := range u.AccessTokens {
+			usr.accessTokens = append(usr.accessTokens, &accessToken{id: s.nextID(), created: time.Now(), AccessToken: t})
+		}
+
+		usr.namespace = &organization{id: usr.id, name: u.Username, members: map[string]string{u.Username: roleOwner}}
+		s.users[u.Username] = usr
+	}
+
+	for _, u := range fixture.Users {
+		for _, r := range u.Repositories {
+			for username := range r.Collaborators {
+				if _, ok := s.users[username]; !ok {
+					t.Fatalf("dockerhubtest: invalid fixture: collaborator %s of %s/%s is not a user", username, u.Username, r.Name)
+				}
+			}
+
+			ns := s.users[u.Username].namespace
+			ns.repositories = append(ns.repositories, &repository{
+				name:          r.Name,
+				description:   r.Description,
+				private:       r.Private,
+				teams:         map[int]string{},
+				collaborators: maps.Clone(r.Collaborators),
+			})
+		}
+	}
+
+	for _, o := range fixture.Organizations {
+		if err := s.addOrganization(o); err != nil {
+			t.Fatalf("dockerhubtest: invalid fixture: %v", err)
+		}
+	}
+
+	s.Server = httptest.NewServer(s.routes())
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Server) addOrganization(o Organization) error {
+	org := &organization{
+		id:        s.nextID(),
+		name:      o.Name,
+		members:   map[string]string{},
+		auditLogs: slices.Clone(o.AuditLogs),
+	}
+
+	for _, t := range o.AccessTokens {
+		org.accessTokens = append(org.accessTokens, &orgAccessToken{
+			id:           s.nextID(),
+			token:        t.Token,
+			label:        t.Label,
+			description:  t.Description,
+			createdBy:    t.CreatedBy,
+			active:       !t.Inactive,
+			created:      time.Now(),
+			expires:      t.ExpiresAt,
+			lastUsed:     t.LastUsedAt,
+			repositories: maps.Clone(t.Repositories),
+		})
+	}
+
+	for _, m := range o.Members {
+		if _, ok := s.users[m.Username]; !ok {
+			return fmt.Errorf("member %s of %s is not a user", m.Username, o.Name)
+		}
+		org.members[m.Username] = m.Role
+	}
+
+	for _, t := range o.Teams {
+		for _, username := range t.Members {
+			if _, ok := org.members[username]; !ok {
+				return fmt.Errorf("member %s of team %s is not a member of %s", username, t.Name, o.Name)
+			}
+		}
+
+		s.ids++
+		org.teams = append(org.teams, &team{
+			id:          s.ids,
+			name:        t.Name,
+			description: t.Description,
+			members:     slices.Clone(t.Members),
+		})
+	}
+
+	for _, r := range o.Repositories {
+		repo := &repository{name: r.Name, description: r.Description, private: r.Private, teams: map[int]string{}}
+		for teamName, permission := range r.Teams {
+			t := org.team(teamName)
+			if t == nil {
+				return fmt.Errorf("team %s with access to %s/%s does not exist", teamName, o.Name, r.Name)
+			}
+			repo.teams[t.id] = permission
+		}
+
+		org.repositories = append(org.repositories, repo)
+	}
+
+	// invitations of the fixture are sent by the first owner
+	var inviter string
+	for _, m := range o.Members {
+		if m.Role == roleOwner {
+			inviter = m.Username
+			break
+		}
+	}
+
+	for _, i := range o.Invites {
+		org.invites = append(org.invites, &invite{id: s.nextID(), inviter: inviter, invitee: i.Invitee, role: i.Role, team: i.Team, created: time.Now()})
+	}
+
+	s.orgs[o.Name] = org
+
+	return nil
+}
+
+func (s *Server) nextID() string {
+	s.ids++
+	return fmt.Sprintf("%032x", s.ids)
+}
+
+func (o *organization) team(name string) *team {
+	for _, t := range o.teams {
+		if t.name == name {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (o *organization) teamByID(id int) *team {
+	for _, t := range o.teams {
+		if t.id == id {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (o *organization) invite(invitee, team string) *invite {
+	for _, i := range o.invites {
+		if strings.EqualFold(i.invitee, invitee) && i.team == team {
+			return i
+		}
+	}
+
+	return nil
+}
+
+// accessToken returns the active personal access token of the user with the given secret.
+func (u *user) accessToken(secret string) *accessToken {
+	for _, t := range u.accessTokens {
+		if secret != "" && t.Token == secret && !t.Inactive && (t.ExpiresAt.IsZero() || t.ExpiresAt.After(time.Now())) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (o *organization) accessToken(match func(*orgAccessToken) bool) *orgAccessToken {
+	for _, t := range o.accessTokens {
+		if match(t) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (o *organization) repository(name string) *repository {
+	for _, r := range o.repositories {
+		if r.name == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// BaseURL returns the URL of the server, to be passed to dockerhub.WithBaseURL.
+func (s *Server) BaseURL() *url.URL {
+	u, err := url.Parse(s.Server.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
+// UserID returns the ID assigned to the user.
+func (s *Server) UserID(username string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if u, ok := s.users[username]; ok {
+		return u.id
+	}
+
+	return ""
+}
+
+// TeamID returns the ID assigned to the team of the organization.
+func (s *Server) TeamID(orgName, teamName string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if org, ok := s.orgs[orgName]; ok {
+		if t := org.team(teamName); t != nil {
+			return t.id
+		}
+	}
+
+	return 0
+}
+
+// Members returns the role of every member of the organization by username.
+func (s *Server) Members(orgName string) map[string]string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if org, ok := s.orgs[orgName]; ok {
+		return maps.Clone(org.members)
+	}
+
+	return nil
+}
+
+// TeamMembers returns the usernames of the members of the team.
+func (s *Server) TeamMembers(orgName, teamName string) []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if org, ok := s.orgs[orgName]; ok {
+		if t := org.team(teamName); t != nil {
+			return slices.Clone(t.members)
+		}
+	}
+
+	return nil
+}
+
+// RepositoryPermissions returns the permission of every team with access to the repository by team name.
+func (s *Server) RepositoryPermissions(orgName, repoName string) map[string]string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	rv := map[string]string{}
+	if org, ok := s.orgs[orgName]; ok {
+		if repo := org.repository(repoName); repo != nil {
+			for teamId, permission := range repo.teams {
+				rv[org.teamByID(teamId).name] = permission
+			}
+		}
+	}
+
+	return rv
+}
+
+// Repository returns the repository of the organization or personal namespace, teams with access to it are keyed by name.
+func (s *Server) Repository(orgName, repoName string) (Repository, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	org, ok := s.orgs[orgName]
+	if !ok {
+		u, found := s.users[orgName]
+		if !found {
+			return Repository{}, false
+		}
+
+		org = u.namespace
+	}
+
+	repo := org.repository(repoName)
+	if repo == nil {
+		return Repository{}, false
+	}
+
+	rv := Repository{Name: repo.name, Description: repo.description, Private: repo.private, Teams: map[string]string{}, Collaborators: maps.Clone(repo.collaborators)}
+	for teamId, permission := range repo.teams {
+		rv.Teams[org.teamByID(teamId).name] = permission
+	}
+
+	return rv, true
+}
+
+// Invites returns the pending invites of the organization.
+func (s *Server) Invites(orgName string) []Invite {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var rv []Invite
+	if org, ok := s.orgs[orgName]; ok {
+		for _, i := range org.invites {
+			rv = append(rv, Invite{Invitee: i.invitee, Role: i.role, Team: i.team})
+		}
+	}
+
+	return rv
+}
+
+// InviteID returns the ID of the pending invitation of the invitee to the organization,
+// or an empty string if there is none.
+func (s *Server) InviteID(orgName, invitee string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if org, ok := s.orgs[orgName]; ok {
+		for _, i := range org.invites {
+			if strings.EqualFold(i.invitee, invitee) {
+				return i.id
+			}
+		}
+	}
+
+	return ""
+}
+
+// OrgAccessTokenID returns the ID of the access token of the organization with the given label,
+// or an empty string if there is none.
+func (s *Server) OrgAccessTokenID(orgName, label string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if org, ok := s.orgs[orgName]; ok {
+		if t := org.accessToken(func(t *orgAccessToken) bool { return t.label == label }); t != nil {
+			return t.id
+		}
+	}
+
+	return ""
+}
+
+// OrgAccessToken returns the access token of the organization with the given ID.
+func (s *Server) OrgAccessToken(orgName, id string) (OrgAccessToken, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	org, ok := s.orgs[orgName]
+	if !ok {
+		return OrgAccessToken{}, false
+	}
+
+	t := org.accessToken(func(t *orgAccessToken) bool { return t.id == id })
+	if t == nil {
+		return OrgAccessToken{}, false
+	}
+
+	return OrgAccessToken{
+		Token:        t.token,
+		Label:        t.label,
+		Description:  t.description,
+		CreatedBy:    t.createdBy,
+		Inactive:     !t.active,
+		ExpiresAt:    t.expires,
+		LastUsedAt:   t.lastUsed,
+		Repositories: maps.Clone(t.repositories),
+	}, true
+}
+
+// Fail makes the server reject requests matching the failure.
+func (s *Server) Fail(f Failure) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.failures = append(s.failures, &f)
+}
+
+// ExpireTokens invalidates every access token issued so far, refresh tokens
+// keep working.
+func (s *Server) ExpireTokens() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.tokens = map[string]principal{}
+}
+
+// Requests returns the "METHOD /path" of every request received so far.
+func (s *Server) Requests() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v2/users/login", s.handleLogin)
+	mux.HandleFunc("POST /v2/auth/token", s.handleAuthToken)
+
+	mux.HandleFunc("GET /v2/user", s.authenticated(s.handleCurrentUser))
+	mux.HandleFunc("GET /v2/users/{username}/orgs", s.authenticated(s.handleUserOrgs))
+	mux.HandleFunc("GET /v2/users/{username}", s.authenticated(s.handleUser))
+	mux.HandleFunc("GET /v2/access-tokens", s.authenticated(s.handleAccessTokens))
+	mux.HandleFunc("GET /v2/auditlogs/{org}", s.managed(s.handleAuditLogs))
+
+	mux.HandleFunc("GET /v2/orgs/{org}", s.authorized(s.handleOrg))
+	mux.HandleFunc("GET /v2/orgs/{org}/members", s.authorized(s.handleMembers))
+	mux.HandleFunc("PUT /v2/orgs/{org}/members/{username}", s.managed(s.handleUpdateMember))
+	mux.HandleFunc("DELETE /v2/orgs/{org}/members/{username}", s.managed(s.handleRemoveMember))
+	mux.HandleFunc("GET /v2/orgs/{org}/invitees", s.authorized(s.handleInvites))
+	mux.HandleFunc("POST /v2/invites/bulk", s.authenticated(s.handleBulkInvite))
+	mux.HandleFunc("DELETE /v2/invites/{id}", s.authenticated(s.handleDeleteInvite))
+	mux.HandleFunc("GET /v2/orgs/{org}/access-tokens", s.managed(s.handleOrgAccessTokens))
+	mux.HandleFunc("POST /v2/orgs/{org}/access-tokens", s.managed(s.handleCreateOrgAccessToken))
+	mux.HandleFunc("GET /v2/orgs/{org}/access-tokens/{id}", s.managed(s.handleOrgAccessToken))
+	mux.HandleFunc("DELETE /v2/orgs/{org}/access-tokens/{id}", s.managed(s.handleDeleteOrgAccessToken))
+	mux.HandleFunc("GET /v2/orgs/{org}/groups", s.authorized(s.handleTeams))
+	mux.HandleFunc("POST /v2/orgs/{org}/groups", s.managed(s.handleCreateTeam))
+	mux.HandleFunc("GET /v2/orgs/{org}/groups/{team}", s.authorized(s.handleTeam))
+	mux.HandleFunc("DELETE /v2/orgs/{org}/groups/{team}", s.managed(s.handleDeleteTeam))
+	mux.HandleFunc("GET /v2/orgs/{org}/groups/{team}/members", s.authorized(s.handleTeamMembers))
+	mux.HandleFunc("POST /v2/orgs/{org}/groups/{team}/members", s.managed(s.handleAddTeamMember))
+	mux.HandleFunc("DELETE /v2/orgs/{org}/groups/{team}/members/{username}", s.managed(s.handleRemoveTeamMember))
+
+	mux.HandleFunc("GET /v2/repositories/{org}", s.namespaceAccess(false, s.handleRepositories))
+	mux.HandleFunc("POST /v2/repositories/{$}", s.authenticated(s.handleCreateRepository))
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}", s.namespaceAccess(false, s.handleRepository))
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}", s.namespaceAccess(true, s.handleDeleteRepository))
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/collaborators", s.namespaceAccess(false, s.handleRepositoryCollaborators))
+	mux.HandleFunc("GET /v2/repositories/{org}/{repo}/groups", s.authorized(s.handleRepositoryTeams))
+	mux.HandleFunc("POST /v2/repositories/{org}/{repo}/groups", s.managed(s.handleAddRepositoryTeam))
+	mux.HandleFunc("PATCH /v2/repositories/{org}/{repo}/groups/{team}", s.managed(s.handleUpdateRepositoryTeam))
+	mux.HandleFunc("DELETE /v2/repositories/{org}/{repo}/groups/{team}", s.managed(s.handleRemoveRepositoryTeam))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.failed(w, r) {
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// failed records the request and writes the first matching failure, if any.
+func (s *Server) failed(w http.ResponseWriter, r *http.Request) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != r.Method) || f.Path != r.URL.Path {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = slices.Delete(s.failures, i, i+1)
+			}
+		}
+
+		for k, v := range f.Header {
+			w.Header()[k] = v
+		}
+
+		body := f.Body
+		if body == "" {
+			body = fmt.Sprintf(`{"detail":%q}`, http.StatusText(f.Status))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.Status)
+		_, _ = w.Write([]byte(body))
+
+		return true
+	}
+
+	return false
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	u, ok := s.users[req.Username]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Incorrect authentication credentials")
+		return
+	}
+
+	p := principal{username: u.Username}
+	switch {
+	case req.RefreshToken != "":
+		var ok bool
+		if p, ok = s.refreshTokens[req.RefreshToken]; !ok || p.username != u.Username {
+			writeError(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+	case req.Password != "" && req.Password == u.Password:
+	default:
+		t := u.accessToken(req.Password)
+		if t == nil {
+			writeError(w, http.StatusUnauthorized, "Incorrect authentication credentials")
+			return
+		}
+
+		t.LastUsedAt = time.Now()
+		p.pat = true
+	}
+
+	token := s.issueToken(p)
+	refreshToken := "refresh-" + token
+	s.refreshTokens[refreshToken] = p
+
+	writeJSON(w, http.StatusOK, map[string]string{"token": token, "refresh_token": refreshToken})
+}
+
+func (s *Server) handleAuthToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Identifier string `json:"identifier"`
+		Secret     string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	org, ok := s.orgs[req.Identifier]
+	if !ok || req.Secret == "" {
+		writeError(w, http.StatusUnauthorized, "Incorrect authentication credentials")
+		return
+	}
+
+	t := org.accessToken(func(t *orgAccessToken) bool { return t.token == req.Secret })
+	if t == nil || !t.active || (!t.expires.IsZero() && t.expires.Before(time.Now())) {
+		writeError(w, http.StatusUnauthorized, "Incorrect authentication credentials")
+		return
+	}
+
+	t.lastUsed = time.Now()
+
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": s.issueToken(principal{org: org.name})})
+}
+
+func (s *Server) issueToken(p principal) string {
+	s.ids++
+	token := fmt.Sprintf("token-%d", s.ids)
+	s.tokens[token] = p
+
+	return token
+}
+
+// authenticated rejects requests without a valid access token. Handlers are
+// called with the server locked.
+func (s *Server) authenticated(next func(http.ResponseWriter, *http.Request, principal)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided")
+			return
+		}
+
+		p, ok := s.tokens[token]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Token is expired or invalid")
+			return
+		}
+
+		next(w, r, p)
+	}
+}
+
+// authorized additionally requires access to the organization in the path,
+// either by membership or by an access token of the organization.
+func (s *Server) authorized(next func(http.ResponseWriter, *http.Request, *organization)) http.HandlerFunc {
+	return s.orgAccess(false, next)
+}
+
+// managed requires the permission to change the organization in the path,
+// which only its owners and its access tokens have.
+func (s *Server) managed(next func(http.ResponseWriter, *http.Request, *organization)) http.HandlerFunc {
+	return s.orgAccess(true, next)
+}
+
+func (s *Server) orgAccess(manage bool, next func(http.ResponseWriter, *http.Request, *organization)) http.HandlerFunc {
+	return s.authenticated(func(w http.ResponseWriter, r *http.Request, p principal) {
+		org, ok := s.orgs[r.PathValue("org")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+
+		if !s.checkAccess(w, p, org, manage) {
+			return
+		}
+
+		next(w, r, org)
+	})
+}
+
+// namespaceAccess is like orgAccess, but the path may also name the personal namespace of a user.
+func (s *Server) namespaceAccess(manage bool, next func(http.ResponseWriter, *http.Request, *organization)) http.HandlerFunc {
+	return s.authenticated(func(w http.ResponseWriter, r *http.Request, p principal) {
+		ns, ok := s.orgs[r.PathValue("org")]
+		if !ok {
+			u, found := s.users[r.PathValue("org")]
+			if !found {
+				writeError(w, http.StatusNotFound, "Namespace not found")
+				return
+			}
+
+			ns = u.namespace
+		}
+
+		if !s.checkAccess(w, p, ns, manage) {
+			return
+		}
+
+		next(w, r, ns)
+	})
+}
+
+// checkAccess writes the error response when the principal can't access the organization.
+func (s *Server) checkAccess(w http.ResponseWriter, p principal, org *organization, manage bool) bool {
+	if p.org != "" && p.org != org.name {
+		writeError(w, http.StatusForbidden, "Access token is not scoped to this organization")
+		return false
+	}
+
+	if p.username != "" {
+		role, ok := org.members[p.username]
+		if !ok {
+			writeError(w, http.StatusForbidden, "User is not a member of this organization")
+			return false
+		}
+
+		if manage && role != roleOwner {
+			writeError(w, http.StatusForbidden, "Only owners can change this organization")
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) handleCurrentUser(w http.ResponseWriter, r *http.Request, p principal) {
+	if p.username == "" {
+		writeError(w, http.StatusForbidden, "Access token is not tied to a user")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.userJSON(s.users[p.username], ""))
+}
+
+func (s *Server) handleUserOrgs(w http.ResponseWriter, r *http.Request, p principal) {
+	username := r.PathValue("username")
+	if p.username != username {
+		writeError(w, http.StatusForbidden, "Not allowed to list organizations of other users")
+		return
+	}
+
+	orgs := []map[string]interface{}{}
+	for _, org := range s.sortedOrgs() {
+		if _, ok := org.members[username]; ok {
+			orgs = append(orgs, orgJSON(org))
+		}
+	}
+
+	writePage(w, r, orgs)
+}
+
+func (s *Server) handleOrg(w http.ResponseWriter, r *http.Request, org *organization) {
+	writeJSON(w, http.StatusOK, orgJSON(org))
+}
+
+func (s *Server) handleMembers(w http.ResponseWriter, r *http.Request, org *organization) {
+	usernames := make([]string, 0, len(org.members))
+	for username := range org.members {
+		usernames = append(usernames, username)
+	}
+	slices.Sort(usernames)
+
+	members := make([]map[string]interface{}, 0, len(usernames))
+	for _, username := range usernames {
+		members = append(members, s.userJSON(s.users[username], org.members[username]))
+	}
+
+	writePage(w, r, members)
+}
+
+func (s *Server) handleUpdateMember(w http.ResponseWriter, r *http.Request, org *organization) {
+	username := r.PathValue("username")
+	if _, ok := org.members[username]; !ok {
+		writeError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !slices.Contains([]string{roleOwner, roleEditor, roleMember}, req.Role) {
+		writeError(w, http.StatusBadRequest, "Invalid role")
+		return
+	}
+
+	org.members[username] = req.Role
+
+	writeJSON(w, http.StatusOK, s.userJSON(s.users[username], req.Role))
+}
+
+func (s *Server) handleRemoveMember(w http.ResponseWriter, r *http.Request, org *organization) {
+	username := r.PathValue("username")
+	if _, ok := org.members[username]; !ok {
+		writeError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	delete(org.members, username)
+	for _, t := range org.teams {
+		t.members = slices.DeleteFunc(t.members, func(m string) bool { return m == username })
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleBulkInvite(w http.ResponseWriter, r *http.Request, p principal) {
+	var req struct {
+		Org      string   `json:"org"`
+		Team     string   `json:"team"`
+		Role     string   `json:"role"`
+		Invitees []string `json:"invitees"`
+		DryRun   bool     `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	org, ok := s.orgs[req.Org]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	if !s.checkAccess(w, p, org, true) {
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = roleMember
+	}
+
+	if req.Team != "" && org.team(req.Team) == nil {
+		writeError(w, http.StatusBadRequest, "Group not found")
+		return
+	}
+
+	results := []map[string]interface{}{}
+	for _, invitee := range req.Invitees {
+		result := map[string]interface{}{"invitee": invitee}
+
+		switch {
+		case s.isMember(org, invitee):
+			result["status"] = "existing_org_member"
+		case !strings.Contains(invitee, "@") && s.users[invitee] == nil:
+			result["status"] = "invalid_invitee"
+		case req.DryRun:
+			result["status"] = "invited"
+		default:
+			i := org.invite(invitee, req.Team)
+			if i == nil {
+				i = &invite{id: s.nextID(), inviter: p.username, invitee: invitee, role: req.Role, team: req.Team, created: time.Now()}
+				org.invites = append(org.invites, i)
+			}
+
+			result["status"] = "invited"
+			result["invite"] = inviteJSON(org, i)
+		}
+
+		results = append(results, result)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"invitees": results})
+}
+
+func (s *Server) handleDeleteInvite(w http.ResponseWriter, r *http.Request, p principal) {
+	id := r.PathValue("id")
+	for _, org := range s.orgs {
+		for _, i := range org.invites {
+			if i.id != id {
+				continue
+			}
+
+			if !s.checkAccess(w, p, org, true) {
+				return
+			}
+
+			org.invites = slices.DeleteFunc(org.invites, func(i *invite) bool { return i.id == id })
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Invite not found")
+}
+
+// isMember reports whether the username or email belongs to a member of the organization.
+func (s *Server) isMember(org *organization, invitee string) bool {
+	for username := range org.members {
+		if u := s.users[username]; strings.EqualFold(u.Username, invitee) || strings.EqualFold(u.Email, invitee) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) handleInvites(w http.ResponseWriter, r *http.Request, org *organization) {
+	invites := make([]map[string]interface{}, 0, len(org.invites))
+	for _, i := range org.invites {
+		invites = append(invites, inviteJSON(org, i))
+	}
+
+	// unlike the other list endpoints, invites are not paginated
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": invites})
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, _ principal) {
+	u, ok := s.users[r.PathValue("username")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	// public profiles don't show the email of the user
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":        u.id,
+		"username":  u.Username,
+		"full_name": u.FullName,
+	})
+}
+
+// handleAuditLogs returns the audit log newest first. Unlike other lists, it doesn't link to the next page.
+func (s *Server) handleAuditLogs(w http.ResponseWriter, r *http.Request, org *organization) {
+	var from time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	entries := slices.Clone(org.auditLogs)
+	slices.SortStableFunc(entries, func(a, b AuditLog) int { return b.Timestamp.Compare(a.Timestamp) })
+
+	logs := []map[string]interface{}{}
+	for _, l := range entries {
+		if l.Timestamp.Before(from) {
+			continue
+		}
+
+		logs = append(logs, map[string]interface{}{
+			"account":   org.name,
+			"action":    l.Action,
+			"name":      l.Name,
+			"actor":     l.Actor,
+			"data":      l.Data,
+			"timestamp": l.Timestamp.UTC().Format(time.RFC3339Nano),
+		})
+	}
+
+	page, size := pageParams(r)
+	start := min((page-1)*size, len(logs))
+	end := min(page*size, len(logs))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"logs": logs[start:end]})
+}
+
+func (s *Server) handleAccessTokens(w http.ResponseWriter, r *http.Request, p principal) {
+	if p.username == "" || p.pat {
+		writeError(w, http.StatusForbidden, "Access tokens can't be managed with an access token")
+		return
+	}
+
+	u := s.users[p.username]
+	tokens := make([]map[string]interface{}, 0, len(u.accessTokens))
+	for _, t := range u.accessTokens {
+		tokens = append(tokens, accessTokenJSON(t))
+	}
+
+	writePage(w, r, tokens)
+}
+
+func (s *Server) handleOrgAccessTokens(w http.ResponseWriter, r *http.Request, org *organization) {
+	tokens := make([]map[string]interface{}, 0, len(org.accessTokens))
+	for _, t := range org.accessTokens {
+		// the resources of a token are only returned by its details
+		tokens = append(tokens, orgAccessTokenJSON(t, false))
+	}
+
+	writePage(w, r, tokens)
+}
+
+func (s *Server) handleOrgAccessToken(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.accessToken(func(t *orgAccessToken) bool { return t.id == r.PathValue("id") })
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Access token not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orgAccessTokenJSON(t, true))
+}
+
+func (s *Server) handleCreateOrgAccessToken(w http.ResponseWriter, r *http.Request, org *organization) {
+	var req struct {
+		Label       string `json:"label"`
+		Description string `json:"description"`
+		Resources   []struct {
+			Type   string   `json:"type"`
+			Path   string   `json:"path"`
+			Scopes []string `json:"scopes"`
+		} `json:"resources"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Label == "" {
+		writeError(w, http.StatusBadRequest, "Label is required")
+		return
+	}
+
+	t := &orgAccessToken{
+		id:           s.nextID(),
+		label:        req.Label,
+		description:  req.Description,
+		active:       true,
+		created:      time.Now(),
+		repositories: map[string][]string{},
+	}
+	t.token = "dckr_oat_" + t.id
+
+	if req.ExpiresAt != nil {
+		t.expires = *req.ExpiresAt
+	}
+
+	for _, res := range req.Resources {
+		if res.Type != "TYPE_REPO" {
+			writeError(w, http.StatusBadRequest, "Unsupported resource type "+res.Type)
+			return
+		}
+		t.repositories[res.Path] = res.Scopes
+	}
+
+	if p, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]; ok {
+		t.createdBy = p.username
+	}
+
+	org.accessTokens = append(org.accessTokens, t)
+
+	rv := orgAccessTokenJSON(t, true)
+	rv["token"] = t.token
+	writeJSON(w, http.StatusCreated, rv)
+}
+
+func (s *Server) handleDeleteOrgAccessToken(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.accessToken(func(t *orgAccessToken) bool { return t.id == r.PathValue("id") })
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Access token not found")
+		return
+	}
+
+	org.accessTokens = slices.DeleteFunc(org.accessTokens, func(o *orgAccessToken) bool { return o == t })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleTeams(w http.ResponseWriter, r *http.Request, org *organization) {
+	teams := make([]map[string]interface{}, 0, len(org.teams))
+	for _, t := range org.teams {
+		teams = append(teams, teamJSON(t))
+	}
+
+	writePage(w, r, teams)
+}
+
+func (s *Server) handleTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, teamJSON(t))
+}
+
+func (s *Server) handleCreateTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "Group name is required")
+		return
+	}
+
+	if org.team(req.Name) != nil {
+		writeError(w, http.StatusConflict, "Group already exists")
+		return
+	}
+
+	s.ids++
+	t := &team{id: s.ids, name: req.Name, description: req.Description}
+	org.teams = append(org.teams, t)
+
+	writeJSON(w, http.StatusCreated, teamJSON(t))
+}
+
+func (s *Server) handleDeleteTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	org.teams = slices.DeleteFunc(org.teams, func(o *team) bool { return o == t })
+	for _, repo := range org.repositories {
+		delete(repo.teams, t.id)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleTeamMembers(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	members := make([]map[string]interface{}, 0, len(t.members))
+	for _, username := range t.members {
+		members = append(members, s.userJSON(s.users[username], ""))
+	}
+
+	writePage(w, r, members)
+}
+
+func (s *Server) handleAddTeamMember(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	var req struct {
+		Member string `json:"member"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := org.members[req.Member]; !ok {
+		writeError(w, http.StatusBadRequest, "User is not a member of this organization")
+		return
+	}
+
+	if slices.Contains(t.members, req.Member) {
+		writeError(w, http.StatusConflict, "User is already a member of this group")
+		return
+	}
+
+	t.members = append(t.members, req.Member)
+
+	writeJSON(w, http.StatusOK, s.userJSON(s.users[req.Member], ""))
+}
+
+func (s *Server) handleRemoveTeamMember(w http.ResponseWriter, r *http.Request, org *organization) {
+	t := org.team(r.PathValue("team"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	username := r.PathValue("username")
+	if !slices.Contains(t.members, username) {
+		writeError(w, http.StatusNotFound, "User is not a member of this group")
+		return
+	}
+
+	t.members = slices.DeleteFunc(t.members, func(m string) bool { return m == username })
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRepositories(w http.ResponseWriter, r *http.Request, org *organization) {
+	repos := make([]map[string]interface{}, 0, len(org.repositories))
+	for _, repo := range org.repositories {
+		repos = append(repos, repositoryJSON(org, repo))
+	}
+
+	writePage(w, r, repos)
+}
+
+func (s *Server) handleRepository(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo := org.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, repositoryJSON(org, repo))
+}
+
+func (s *Server) handleCreateRepository(w http.ResponseWriter, r *http.Request, p principal) {
+	var req struct {
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		IsPrivate   bool   `json:"is_private"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	org, ok := s.orgs[req.Namespace]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Namespace not found")
+		return
+	}
+
+	if !s.checkAccess(w, p, org, true) {
+		return
+	}
+
+	if req.Name == "" || strings.ToLower(req.Name) != req.Name {
+		writeError(w, http.StatusBadRequest, "Repository name must be lowercase and not empty")
+		return
+	}
+
+	if org.repository(req.Name) != nil {
+		writeError(w, http.StatusConflict, "Repository already exists")
+		return
+	}
+
+	repo := &repository{name: req.Name, description: req.Description, private: req.IsPrivate, teams: map[int]string{}}
+	org.repositories = append(org.repositories, repo)
+
+	writeJSON(w, http.StatusCreated, repositoryJSON(org, repo))
+}
+
+func (s *Server) handleDeleteRepository(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo := org.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+
+	org.repositories = slices.DeleteFunc(org.repositories, func(o *repository) bool { return o == repo })
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleRepositoryCollaborators lists the users with access to a personal repository.
+func (s *Server) handleRepositoryCollaborators(w http.ResponseWriter, r *http.Request, ns *organization) {
+	repo := ns.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+
+	collaborators := make([]map[string]interface{}, 0, len(repo.collaborators))
+	for _, username := range slices.Sorted(maps.Keys(repo.collaborators)) {
+		collaborators = append(collaborators, map[string]interface{}{
+			"user":       username,
+			"permission": repo.collaborators[username],
+		})
+	}
+
+	writePage(w, r, collaborators)
+}
+
+func (s *Server) handleRepositoryTeams(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo := org.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+
+	perms := []map[string]interface{}{}
+	for _, t := range org.teams {
+		if permission, ok := repo.teams[t.id]; ok {
+			perms = append(perms, repositoryTeamJSON(t, permission))
+		}
+	}
+
+	writePage(w, r, perms)
+}
+
+func (s *Server) handleAddRepositoryTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo := org.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+
+	var req struct {
+		TeamId     int    `json:"group_id"`
+		Permission string `json:"permission"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t := org.teamByID(req.TeamId)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	if !validPermission(req.Permission) {
+		writeError(w, http.StatusBadRequest, "Invalid permission")
+		return
+	}
+
+	if _, ok := repo.teams[t.id]; ok {
+		writeError(w, http.StatusConflict, "Group already has access to this repository")
+		return
+	}
+
+	repo.teams[t.id] = req.Permission
+
+	writeJSON(w, http.StatusCreated, repositoryTeamJSON(t, req.Permission))
+}
+
+func (s *Server) handleUpdateRepositoryTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo, t, ok := repositoryTeam(w, r, org)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Permission string `json:"permission"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !validPermission(req.Permission) {
+		writeError(w, http.StatusBadRequest, "Invalid permission")
+		return
+	}
+
+	repo.teams[t.id] = req.Permission
+
+	writeJSON(w, http.StatusOK, repositoryTeamJSON(t, req.Permission))
+}
+
+func (s *Server) handleRemoveRepositoryTeam(w http.ResponseWriter, r *http.Request, org *organization) {
+	repo, t, ok := repositoryTeam(w, r, org)
+	if !ok {
+		return
+	}
+
+	delete(repo.teams, t.id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// repositoryTeam looks up the repository and the team with access to it addressed by the path.
+func repositoryTeam(w http.ResponseWriter, r *http.Request, org *organization) (*repository, *team, bool) {
+	repo := org.repository(r.PathValue("repo"))
+	if repo == nil {
+		writeError(w, http.StatusNotFound, "Repository not found")
+		return nil, nil, false
+	}
+
+	teamId, err := strconv.Atoi(r.PathValue("team"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return nil, nil, false
+	}
+
+	t := org.teamByID(teamId)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Group not found")
+		return nil, nil, false
+	}
+
+	if _, ok := repo.teams[t.id]; !ok {
+		writeError(w, http.StatusNotFound, "Group has no access to this repository")
+		return nil, nil, false
+	}
+
+	return repo, t, true
+}
+
+func validPermission(permission string) bool {
+	return slices.Contains([]string{"read", "write", "admin"}, permission)
+}
+
+func (s *Server) sortedOrgs() []*organization {
+	orgs := make([]*organization, 0, len(s.orgs))
+	for _, org := range s.orgs {
+		orgs = append(orgs, org)
+	}
+	slices.SortFunc(orgs, func(a, b *organization) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	return orgs
+}
+
+func (s *Server) userJSON(u *user, role string) map[string]interface{} {
+	rv := map[string]interface{}{
+		"id":        u.id,
+		"username":  u.Username,
+		"full_name": u.FullName,
+		"email":     u.Email,
+	}
+	if role != "" {
+		rv["role"] = role
+	}
+
+	return rv
+}
+
+func orgJSON(org *organization) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      org.id,
+		"orgname": org.name,
+	}
+}
+
+func inviteJSON(org *organization, i *invite) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               i.id,
+		"inviter_username": i.inviter,
+		"invitee":          i.invitee,
+		"org":              org.name,
+		"role":             i.role,
+		"team":             i.team,
+		"created_at":       i.created.UTC().Format(time.RFC3339),
+	}
+}
+
+func repositoryTeamJSON(t *team, permission string) map[string]interface{} {
+	return map[string]interface{}{
+		"group_id":   t.id,
+		"group_name": t.name,
+		"permission": permission,
+	}
+}
+
+func accessTokenJSON(t *accessToken) map[string]interface{} {
+	scopes := t.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"repo:admin"}
+	}
+
+	return map[string]interface{}{
+		"uuid":         t.id,
+		"token_label":  t.Label,
+		"scopes":       scopes,
+		"is_active":    !t.Inactive,
+		"generated_by": "manual",
+		"created_at":   t.created.UTC().Format(time.RFC3339),
+		"last_used":    timeJSON(t.LastUsedAt),
+		"expires_at":   timeJSON(t.ExpiresAt),
+		// the secret is only returned when the token is created
+		"token": "",
+	}
+}
+
+func orgAccessTokenJSON(t *orgAccessToken, withResources bool) map[string]interface{} {
+	rv := map[string]interface{}{
+		"id":           t.id,
+		"label":        t.label,
+		"description":  t.description,
+		"created_by":   t.createdBy,
+		"is_active":    t.active,
+		"created_at":   t.created.UTC().Format(time.RFC3339),
+		"expires_at":   timeJSON(t.expires),
+		"last_used_at": timeJSON(t.lastUsed),
+	}
+
+	if withResources {
+		resources := []map[string]interface{}{}
+		for _, path := range slices.Sorted(maps.Keys(t.repositories)) {
+			resources = append(resources, map[string]interface{}{
+				"type":   "TYPE_REPO",
+				"path":   path,
+				"scopes": t.repositories[path],
+			})
+		}
+		rv["resources"] = resources
+	}
+
+	return rv
+}
+
+// timeJSON formats the time like DockerHub does, unset times are null.
+func timeJSON(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func repositoryJSON(org *organization, repo *repository) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        repo.name,
+		"namespace":   org.name,
+		"description": repo.description,
+		"is_private":  repo.private,
+	}
+}
+
+func teamJSON(t *team) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           t.id,
+		"name":         t.name,
+		"description":  t.description,
+		"member_count": len(t.members),
+	}
+}
+
+// writePage writes the page of items selected by the page and page_size query
+// parameters, linking to the next page when there is one.
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	query := r.URL.Query()
+	page, size := pageParams(r)
+
+	start := min((page-1)*size, len(items))
+	end := min(page*size, len(items))
+
+	response := map[string]interface{}{
+		"count":    len(items),
+		"next":     nil,
+		"previous": nil,
+		"results":  items[start:end],
+	}
+
+	if end < len(items) {
+		next := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		response["next"] = next.String()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// pageParams returns the page and page size of the request.
+func pageParams(r *http.Request) (int, int) {
+	query := r.URL.Query()
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	size, err := strconv.Atoi(query.Get("page_size"))
+	if err != nil || size < 1 {
+		size = defaultPageSize
+	}
+
+	return page, size
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
@@ -0,0 +1,117 @@
+package dockerhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is returned for every DockerHub API response with a non-2xx status.
+// It carries the decoded error body and maps to a gRPC status code, so callers
+// can use status.Code(err) to tell the failures apart.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Method     string `json:"-"`
+	Endpoint   string `json:"-"`
+
+	Detail  string                 `json:"detail"`
+	Message string                 `json:"message"`
+	ErrInfo map[string]interface{} `json:"errinfo"`
+
+	rateLimit *v2.RateLimitDescription
+	cause     error
+}
+
+// newAPIError decodes the error body of an unsuccessful response. The cause is
+// the error returned by the HTTP client, which is returned as is when there is no
+// response to decode.
+func newAPIError(req *http.Request, resp *http.Response, cause error) error {
+	if resp == nil || resp.StatusCode < 300 {
+		return cause
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		Endpoint:   req.URL.Path,
+		cause:      cause,
+	}
+
+	// not every error comes with a JSON body, the status alone has to do then
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		_ = json.Unmarshal(body, apiErr)
+	}
+
+	rateLimit, err := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header)
+	if err == nil {
+		apiErr.rateLimit = rateLimit
+	}
+
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	reason := e.Detail
+	if reason == "" {
+		reason = e.Message
+	}
+	if reason == "" {
+		reason = http.StatusText(e.StatusCode)
+	}
+
+	return fmt.Sprintf("dockerhub: %s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, reason)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// Code maps the HTTP status of the response to a gRPC status code.
+func (e *APIError) Code() codes.Code {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+
+	// the syncer only backs off and retries Unavailable, pacing itself with the
+	// rate limit details of the status, so a rate limit must not abort the sync
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// GRPCStatus makes the error usable with status.Code and status.FromError.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Error())
+	if e.rateLimit == nil {
+		return st
+	}
+
+	withDetails, err := st.WithDetails(e.rateLimit)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
@@ -0,0 +1,162 @@
+package dockerhub
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testAPIError(t *testing.T, statusCode int, body string, header http.Header) *APIError {
+	t.Helper()
+
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/v2/orgs/acme/members"}}
+	if header == nil {
+		header = http.Header{}
+	}
+	resp := &http.Response{StatusCode: statusCode, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+
+	var apiErr *APIError
+	if !errors.As(newAPIError(req, resp, nil), &apiErr) {
+		t.Fatalf("expected an APIError for status %d", statusCode)
+	}
+
+	return apiErr
+}
+
+func TestAPIErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantErrInfo map[string]interface{}
+	}{
+		{
+			name:        "detail",
+			body:        `{"detail": "Incorrect authentication credentials."}`,
+			wantMessage: "dockerhub: GET /v2/orgs/acme/members: 401 Incorrect authentication credentials.",
+		},
+		{
+			name:        "message",
+			body:        `{"message": "user not found"}`,
+			wantMessage: "dockerhub: GET /v2/orgs/acme/members: 401 user not found",
+		},
+		{
+			name:        "detail wins over message",
+			body:        `{"detail": "token expired", "message": "unauthorized"}`,
+			wantMessage: "dockerhub: GET /v2/orgs/acme/members: 401 token expired",
+		},
+		{
+			name:        "errinfo",
+			body:        `{"detail": "Two-factor authentication required", "errinfo": {"login_2fa_token": "abc"}}`,
+			wantMessage: "dockerhub: GET /v2/orgs/acme/members: 401 Two-factor authentication required",
+			wantErrInfo: map[string]interface{}{"login_2fa_token": "abc"},
+		},
+		{
+			name:        "not json",
+			body:        `<html>Unauthorized</html>`,
+			wantMessage: "dockerhub: GET /v2/orgs/acme/members: 401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := testAPIError(t, http.StatusUnauthorized, tt.body, nil)
+
+			if got := apiErr.Error(); got != tt.wantMessage {
+				t.Errorf("expected message %q, got %q", tt.wantMessage, got)
+			}
+			if len(apiErr.ErrInfo) != len(tt.wantErrInfo) {
+				t.Fatalf("expected errinfo %v, got %v", tt.wantErrInfo, apiErr.ErrInfo)
+			}
+			for k, v := range tt.wantErrInfo {
+				if apiErr.ErrInfo[k] != v {
+					t.Errorf("expected errinfo %s to be %v, got %v", k, v, apiErr.ErrInfo[k])
+				}
+			}
+		})
+	}
+}
+
+func TestAPIErrorCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       codes.Code
+	}{
+		{http.StatusBadRequest, codes.InvalidArgument},
+		{http.StatusUnprocessableEntity, codes.InvalidArgument},
+		{http.StatusUnauthorized, codes.Unauthenticated},
+		{http.StatusForbidden, codes.PermissionDenied},
+		{http.StatusNotFound, codes.NotFound},
+		{http.StatusConflict, codes.AlreadyExists},
+		{http.StatusRequestTimeout, codes.DeadlineExceeded},
+		{http.StatusTooManyRequests, codes.Unavailable},
+		{http.StatusNotImplemented, codes.Unimplemented},
+		{http.StatusInternalServerError, codes.Unavailable},
+		{http.StatusBadGateway, codes.Unavailable},
+		{http.StatusServiceUnavailable, codes.Unavailable},
+		{http.StatusTeapot, codes.Unknown},
+		{http.StatusMultipleChoices, codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			apiErr := testAPIError(t, tt.statusCode, "", nil)
+
+			if got := apiErr.Code(); got != tt.want {
+				t.Errorf("expected %d to map to %s, got %s", tt.statusCode, tt.want, got)
+			}
+			if got := status.Code(apiErr); got != tt.want {
+				t.Errorf("expected status.Code to return %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAPIErrorRateLimitDetails(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "180")
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", "1700000000")
+
+	apiErr := testAPIError(t, http.StatusTooManyRequests, `{"detail": "Rate limit exceeded"}`, header)
+
+	st, ok := status.FromError(apiErr)
+	if !ok {
+		t.Fatal("expected the error to carry a gRPC status")
+	}
+	if st.Code() != codes.Unavailable {
+		t.Errorf("expected Unavailable so that the syncer retries, got %s", st.Code())
+	}
+
+	var rateLimit *v2.RateLimitDescription
+	for _, detail := range st.Details() {
+		if rl, ok := detail.(*v2.RateLimitDescription); ok {
+			rateLimit = rl
+		}
+	}
+	if rateLimit == nil {
+		t.Fatalf("expected rate limit details, got %v", st.Details())
+	}
+
+	if rateLimit.Status != v2.RateLimitDescription_STATUS_OVERLIMIT || rateLimit.Limit != 180 || rateLimit.Remaining != 0 {
+		t.Errorf("expected an over limit description with a limit of 180, got %v", rateLimit)
+	}
+	if got := rateLimit.ResetAt.AsTime().Unix(); got != 1700000000 {
+		t.Errorf("expected the limit to reset at 1700000000, got %d", got)
+	}
+
+	// errors without rate limit headers carry none
+	st, _ = status.FromError(testAPIError(t, http.StatusNotFound, "", nil))
+	for _, detail := range st.Details() {
+		if rl, ok := detail.(*v2.RateLimitDescription); ok && rl.Status == v2.RateLimitDescription_STATUS_OVERLIMIT {
+			t.Errorf("expected no over limit details on a 404, got %v", rl)
+		}
+	}
+}
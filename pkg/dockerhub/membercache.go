@@ -0,0 +1,89 @@
+package dockerhub
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// DefaultMemberCacheTTL is how long the members of an organization are remembered by IsOrganizationMember.
+const DefaultMemberCacheTTL = 5 * time.Minute
+
+// memberCache remembers the usernames of the members of each organization, so that users can be
+// attributed to a single organization without listing the members again for every page.
+type memberCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mtx  sync.Mutex
+	orgs map[string]*memberDirectory
+}
+
+// memberDirectory holds the lowercase usernames of the members of an organization.
+type memberDirectory struct {
+	usernames map[string]struct{}
+	loadedAt  time.Time
+}
+
+func newMemberCache(ttl time.Duration) *memberCache {
+	return &memberCache{
+		ttl:  ttl,
+		now:  time.Now,
+		orgs: make(map[string]*memberDirectory),
+	}
+}
+
+// lookup returns the usernames of the members of the organization, or nil when they weren't listed within the TTL.
+func (mc *memberCache) lookup(orgSlug string) map[string]struct{} {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	dir, ok := mc.orgs[orgSlug]
+	if !ok || mc.now().Sub(dir.loadedAt) > mc.ttl {
+		return nil
+	}
+
+	return dir.usernames
+}
+
+func (mc *memberCache) store(orgSlug string, usernames map[string]struct{}) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	mc.orgs[orgSlug] = &memberDirectory{usernames: usernames, loadedAt: mc.now()}
+}
+
+// invalidate forgets the members of the organization, e.g. after a member was removed.
+func (mc *memberCache) invalidate(orgSlug string) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	delete(mc.orgs, orgSlug)
+}
+
+// IsOrganizationMember reports whether the user is a member of the organization. The members of the
+// organization are listed on the first lookup and remembered for the TTL of the client.
+func (c *Client) IsOrganizationMember(ctx context.Context, orgSlug, username string) (bool, *v2.RateLimitDescription, error) {
+	usernames := c.members.lookup(orgSlug)
+	if usernames == nil {
+		p := NewPaginator[User](c, &PaginationVars{Size: 100}, UsersEndpoint, orgSlug)
+
+		usernames = make(map[string]struct{})
+		for member, err := range p.All(ctx) {
+			if err != nil {
+				return false, p.RateLimit(), err
+			}
+
+			usernames[strings.ToLower(member.Username)] = struct{}{}
+		}
+
+		c.members.store(orgSlug, usernames)
+	}
+
+	_, ok := usernames[strings.ToLower(username)]
+
+	return ok, nil, nil
+}
@@ -0,0 +1,36 @@
+package dockerhub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemberCacheExpires(t *testing.T) {
+	now := time.Now()
+	mc := newMemberCache(time.Minute)
+	mc.now = func() time.Time { return now }
+
+	if mc.lookup("acme") != nil {
+		t.Fatal("expected a miss before the members are listed")
+	}
+
+	mc.store("acme", map[string]struct{}{"alice": {}})
+
+	if _, ok := mc.lookup("acme")["alice"]; !ok {
+		t.Error("expected alice to be a member of acme")
+	}
+	if mc.lookup("globex") != nil {
+		t.Error("expected members to be cached per organization")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if mc.lookup("acme") != nil {
+		t.Error("expected a miss once the TTL passed")
+	}
+
+	mc.store("acme", map[string]struct{}{"alice": {}})
+	mc.invalidate("acme")
+	if mc.lookup("acme") != nil {
+		t.Error("expected a miss after invalidating the organization")
+	}
+}
@@ -32,6 +32,14 @@ type Repository struct {
 	Name        string `json:"name"`
 	NameSpace   string `json:"namespace"`
 	Description string `json:"description"`
+	IsPrivate   bool   `json:"is_private"`
+}
+
+type CreateRepositoryReq struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsPrivate   bool   `json:"is_private"`
 }
 
 type RepositoryPermission struct {
@@ -39,3 +47,112 @@ type RepositoryPermission struct {
 	TeamName   string `json:"group_name"`
 	Permission string `json:"permission"`
 }
+
+// Collaborator is a user with access to a repository of a personal namespace.
+type Collaborator struct {
+	Username   string `json:"user"`
+	Permission string `json:"permission"`
+}
+
+type RepositoryPermissionReq struct {
+	TeamId     int    `json:"group_id,omitempty"`
+	Permission string `json:"permission"`
+}
+
+type UpdateMemberRoleReq struct {
+	Role string `json:"role"`
+}
+
+type CreateTeamReq struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type AddTeamMemberReq struct {
+	Member string `json:"member"`
+}
+
+type BulkInviteReq struct {
+	Org      string   `json:"org"`
+	Team     string   `json:"team,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Invitees []string `json:"invitees"`
+	DryRun   bool     `json:"dry_run"`
+}
+
+type BulkInviteResp struct {
+	Invitees []InviteeResult `json:"invitees"`
+}
+
+// InviteeResult is the outcome of inviting a single user, Status is e.g.
+// invited or existing_org_member.
+type InviteeResult struct {
+	Invitee string  `json:"invitee"`
+	Status  string  `json:"status"`
+	Invite  *Invite `json:"invite"`
+}
+
+type InvitesResp struct {
+	Data []Invite `json:"data"`
+}
+
+type Invite struct {
+	Id        string `json:"id"`
+	Inviter   string `json:"inviter_username"`
+	Invitee   string `json:"invitee"`
+	Org       string `json:"org"`
+	Team      string `json:"team"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AccessToken struct {
+	Uuid        string   `json:"uuid"`
+	Label       string   `json:"token_label"`
+	Scopes      []string `json:"scopes"`
+	IsActive    bool     `json:"is_active"`
+	GeneratedBy string   `json:"generated_by"`
+	CreatedAt   string   `json:"created_at"`
+	LastUsed    string   `json:"last_used"`
+	ExpiresAt   string   `json:"expires_at"`
+}
+
+type OrgAccessToken struct {
+	Id          string                   `json:"id"`
+	Label       string                   `json:"label"`
+	Description string                   `json:"description"`
+	CreatedBy   string                   `json:"created_by"`
+	IsActive    bool                     `json:"is_active"`
+	CreatedAt   string                   `json:"created_at"`
+	ExpiresAt   string                   `json:"expires_at"`
+	LastUsedAt  string                   `json:"last_used_at"`
+	Resources   []OrgAccessTokenResource `json:"resources"`
+	Token       string                   `json:"token"`
+}
+
+type CreateOrgAccessTokenReq struct {
+	Label       string                   `json:"label"`
+	Description string                   `json:"description,omitempty"`
+	Resources   []OrgAccessTokenResource `json:"resources"`
+	ExpiresAt   string                   `json:"expires_at,omitempty"`
+}
+
+type OrgAccessTokenResource struct {
+	Type   string   `json:"type"`
+	Path   string   `json:"path"`
+	Scopes []string `json:"scopes"`
+}
+
+type AuditLogsResp struct {
+	Logs []AuditLog `json:"logs"`
+}
+
+type AuditLog struct {
+	Account           string            `json:"account"`
+	Action            string            `json:"action"`
+	Name              string            `json:"name"`
+	Actor             string            `json:"actor"`
+	Data              map[string]string `json:"data"`
+	Timestamp         string            `json:"timestamp"`
+	ActionDescription string            `json:"action_description"`
+}
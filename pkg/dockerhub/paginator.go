@@ -0,0 +1,143 @@
+package dockerhub
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// Paginator walks a paginated DockerHub list endpoint, following the next link
+// returned with every page until there is none.
+type Paginator[T any] struct {
+	client   *Client
+	endpoint string
+	params   []interface{}
+
+	size      uint
+	page      string
+	count     int
+	done      bool
+	rateLimit *v2.RateLimitDescription
+}
+
+// NewPaginator creates a paginator over the endpoint, formatted with params the
+// same way as the endpoint constants of this package, e.g.
+//
+//	NewPaginator[User](client, &PaginationVars{Size: 100}, UsersEndpoint, "my-org")
+//
+// The page of pVars is the cursor to start from, so a walk can be resumed with
+// the value returned by Cursor. Nil pVars start from the first page with the
+// default page size of the API.
+func NewPaginator[T any](c *Client, pVars *PaginationVars, endpoint string, params ...interface{}) *Paginator[T] {
+	p := &Paginator[T]{
+		client:   c,
+		endpoint: endpoint,
+		params:   params,
+	}
+
+	if pVars != nil {
+		p.size = pVars.Size
+		p.page = pVars.Page
+	}
+
+	return p
+}
+
+// HasNext reports whether there are pages left.
+func (p *Paginator[T]) HasNext() bool {
+	return !p.done
+}
+
+// Cursor returns the page the next call to Next fetches.
+func (p *Paginator[T]) Cursor() string {
+	return p.page
+}
+
+// Count returns the total number of items as reported with the last fetched page.
+func (p *Paginator[T]) Count() int {
+	return p.count
+}
+
+// RateLimit returns the rate limit state reported with the last fetched page.
+func (p *Paginator[T]) RateLimit() *v2.RateLimitDescription {
+	return p.rateLimit
+}
+
+// Next fetches the next page. It returns no items once all pages were fetched.
+func (p *Paginator[T]) Next(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	response, rateLimitData, err := fetchPage[T](ctx, p.client, &PaginationVars{Size: p.size, Page: p.page}, p.endpoint, p.params...)
+	p.rateLimit = rateLimitData
+	if err != nil {
+		return nil, err
+	}
+
+	p.count = response.Count
+	p.page = parsePageFromURL(response.Next)
+	p.done = p.page == ""
+
+	return response.Results, nil
+}
+
+// All iterates over the items of all remaining pages. The iteration stops after
+// yielding the first error, which includes the context getting canceled.
+func (p *Paginator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for p.HasNext() {
+			items, err := p.Next(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ListAll iterates over every item of the endpoint, fetching pages of the given size.
+func ListAll[T any](ctx context.Context, c *Client, pageSize uint, endpoint string, params ...interface{}) iter.Seq2[T, error] {
+	return NewPaginator[T](c, &PaginationVars{Size: pageSize}, endpoint, params...).All(ctx)
+}
+
+func fetchPage[T any](ctx context.Context, c *Client, pVars *PaginationVars, endpoint string, params ...interface{}) (*ListResponse[T], *v2.RateLimitDescription, error) {
+	var response ListResponse[T]
+
+	rateLimitData, err := c.doRequest(
+		ctx,
+		http.MethodGet,
+		c.composeURL(endpoint, params...),
+		&response,
+		nil,
+		pVars,
+	)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	return &response, rateLimitData, nil
+}
+
+// listPage fetches a single page, returning the page to continue with.
+func listPage[T any](ctx context.Context, c *Client, pVars *PaginationVars, endpoint string, params ...interface{}) ([]T, string, *v2.RateLimitDescription, error) {
+	response, rateLimitData, err := fetchPage[T](ctx, c, pVars, endpoint, params...)
+	if err != nil {
+		return nil, "", rateLimitData, err
+	}
+
+	return response.Results, parsePageFromURL(response.Next), rateLimitData, nil
+}
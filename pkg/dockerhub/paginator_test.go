@@ -0,0 +1,83 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPaginatorAll(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(ctx, t, newOrgsServer(t, 7))
+
+	p := NewPaginator[Organization](client, &PaginationVars{Size: 3}, UserOrgsEndpoint, "jdoe")
+
+	var names []string
+	for org, err := range p.All(ctx) {
+		if err != nil {
+			t.Fatalf("failed to list organizations: %v", err)
+		}
+		names = append(names, org.Name)
+	}
+
+	if len(names) != 7 {
+		t.Fatalf("expected 7 organizations, got %d: %v", len(names), names)
+	}
+	if p.Count() != 7 {
+		t.Errorf("expected count 7, got %d", p.Count())
+	}
+	if p.HasNext() {
+		t.Error("expected no pages left")
+	}
+}
+
+func TestPaginatorResumeFromCursor(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(ctx, t, newOrgsServer(t, 7))
+
+	first := NewPaginator[Organization](client, &PaginationVars{Size: 3}, UserOrgsEndpoint, "jdoe")
+	if _, err := first.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := NewPaginator[Organization](client, &PaginationVars{Size: 3, Page: first.Cursor()}, UserOrgsEndpoint, "jdoe")
+
+	var names []string
+	for org, err := range resumed.All(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, org.Name)
+	}
+
+	if len(names) != 4 || names[0] != "org-3" {
+		t.Fatalf("expected to resume with org-3 and get 4 organizations, got %v", names)
+	}
+}
+
+func TestListAllStopsOnCanceledContext(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(ctx, t, newOrgsServer(t, 7))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	count := 0
+	var lastErr error
+	for _, err := range ListAll[Organization](ctx, client, 3, UserOrgsEndpoint, "jdoe") {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		count++
+		cancel()
+	}
+
+	if !errors.Is(lastErr, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", lastErr)
+	}
+	if count != 3 {
+		t.Fatalf("expected only the first page of 3 organizations, got %d", count)
+	}
+}
@@ -0,0 +1,51 @@
+package dockerhub
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries is how many times a request rejected with 429 is retried.
+	maxRateLimitRetries = 3
+	// maxRetryDelay caps how long a single retry waits, regardless of what the API asks for.
+	maxRetryDelay = time.Minute
+	// defaultRetryDelay is the base of the exponential backoff used when the API
+	// doesn't say when the limit resets.
+	defaultRetryDelay = time.Second
+)
+
+// retryDelay returns how long to wait before retrying a rate limited request,
+// honoring Retry-After first and the X-RateLimit-Reset timestamp second.
+func retryDelay(resp *http.Response, attempt int, now time.Time) time.Duration {
+	delay := defaultRetryDelay << attempt
+
+	if seconds, err := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if retryAt, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		delay = retryAt.Sub(now)
+	} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		delay = time.Unix(reset, 0).Sub(now)
+	}
+
+	if delay < 0 {
+		return 0
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// sleep waits for the given duration unless the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
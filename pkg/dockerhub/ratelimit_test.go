@@ -0,0 +1,149 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		attempt int
+		want    time.Duration
+	}{
+		{
+			name:    "retry after seconds",
+			headers: map[string]string{"Retry-After": "30"},
+			want:    30 * time.Second,
+		},
+		{
+			name:    "retry after http date",
+			headers: map[string]string{"Retry-After": now.Add(45 * time.Second).UTC().Format(http.TimeFormat)},
+			want:    45 * time.Second,
+		},
+		{
+			name:    "retry after wins over rate limit reset",
+			headers: map[string]string{"Retry-After": "5", "X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Minute).Unix(), 10)},
+			want:    5 * time.Second,
+		},
+		{
+			name:    "rate limit reset",
+			headers: map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(20*time.Second).Unix(), 10)},
+			want:    20 * time.Second,
+		},
+		{
+			name:    "rate limit reset in the past",
+			headers: map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)},
+			want:    0,
+		},
+		{
+			name:    "retry after capped",
+			headers: map[string]string{"Retry-After": "3600"},
+			want:    maxRetryDelay,
+		},
+		{
+			name:    "rate limit reset capped",
+			headers: map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)},
+			want:    maxRetryDelay,
+		},
+		{
+			name: "backoff first attempt",
+			want: defaultRetryDelay,
+		},
+		{
+			name:    "backoff third attempt",
+			attempt: 2,
+			want:    4 * defaultRetryDelay,
+		},
+		{
+			name:    "backoff capped",
+			attempt: 10,
+			want:    maxRetryDelay,
+		},
+		{
+			name:    "unparsable retry after backs off",
+			headers: map[string]string{"Retry-After": "soon"},
+			attempt: 1,
+			want:    2 * defaultRetryDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+
+			if got := retryDelay(resp, tt.attempt, now); got != tt.want {
+				t.Errorf("expected a delay of %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSleep(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		delay   time.Duration
+		wantErr error
+	}{
+		{
+			name:  "waits",
+			ctx:   func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			delay: time.Millisecond,
+		},
+		{
+			name: "canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			delay:   time.Hour,
+			wantErr: context.Canceled,
+		},
+		{
+			name: "canceled while sleeping",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			delay:   time.Hour,
+			wantErr: context.Canceled,
+		},
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			delay:   time.Hour,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			err := sleep(ctx, tt.delay)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected sleep to return early, took %s", elapsed)
+			}
+		})
+	}
+}
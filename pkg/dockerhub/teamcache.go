@@ -0,0 +1,172 @@
+package dockerhub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultTeamCacheTTL is how long the teams of an organization are remembered before they are listed again.
+const DefaultTeamCacheTTL = 5 * time.Minute
+
+const teamCachePageSize = 100
+
+// teamCache maps team names to IDs per organization. Repository permissions only name the team,
+// so without it every permission would take a request to look up the team.
+type teamCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mtx    sync.Mutex
+	orgs   map[string]*teamDirectory
+	hits   int
+	misses int
+}
+
+// teamDirectory holds the IDs of the teams of an organization, by name, and when the teams were last
+// listed again to look for a missing team.
+type teamDirectory struct {
+	ids      map[string]int
+	loadedAt time.Time
+	missed   map[string]time.Time
+}
+
+func newTeamCache(ttl time.Duration) *teamCache {
+	return &teamCache{
+		ttl:  ttl,
+		now:  time.Now,
+		orgs: make(map[string]*teamDirectory),
+	}
+}
+
+// lookup returns the ID of the team, whether it is known without listing the teams again, and whether
+// the teams of the organization were listed within the TTL. A team missing from them is known once they
+// were listed again for it within the TTL. The team ID is 0 when there is no such team.
+func (tc *teamCache) lookup(orgSlug, teamName string) (int, bool, bool) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	now := tc.now()
+	dir, ok := tc.orgs[orgSlug]
+	if !ok || now.Sub(dir.loadedAt) > tc.ttl {
+		return 0, false, false
+	}
+
+	if id, ok := dir.ids[teamName]; ok {
+		return id, true, true
+	}
+
+	// the team may have been created since, list the teams again once per TTL to find out
+	if missedAt, ok := dir.missed[teamName]; ok && now.Sub(missedAt) <= tc.ttl {
+		return 0, true, true
+	}
+	dir.missed[teamName] = now
+
+	return 0, false, true
+}
+
+// store remembers the teams of the organization, keeping track of the teams recently looked for in vain.
+func (tc *teamCache) store(orgSlug string, ids map[string]int) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	now := tc.now()
+	missed := make(map[string]time.Time)
+	if dir, ok := tc.orgs[orgSlug]; ok {
+		for teamName, missedAt := range dir.missed {
+			if now.Sub(missedAt) <= tc.ttl {
+				missed[teamName] = missedAt
+			}
+		}
+	}
+
+	tc.orgs[orgSlug] = &teamDirectory{ids: ids, loadedAt: now, missed: missed}
+}
+
+// invalidate forgets the teams of the organization, e.g. after a team was created or deleted.
+func (tc *teamCache) invalidate(orgSlug string) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	delete(tc.orgs, orgSlug)
+}
+
+// count records a hit or a miss, returning the totals so far.
+func (tc *teamCache) count(hit bool) (int, int) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	if hit {
+		tc.hits++
+	} else {
+		tc.misses++
+	}
+
+	return tc.hits, tc.misses
+}
+
+// ResolveTeamID returns the ID of the team with the given name. The teams of the organization are
+// listed on the first lookup and remembered for the TTL of the client. A team missing from them may
+// have been created since, so they are listed again before the lookup fails with a NotFound error,
+// at most once per TTL for every missing team, e.g. a deleted team named by many audit logs.
+func (c *Client) ResolveTeamID(ctx context.Context, orgSlug, teamName string) (int, *v2.RateLimitDescription, error) {
+	var rateLimitData *v2.RateLimitDescription
+
+	id, hit, listed := c.teams.lookup(orgSlug, teamName)
+	if !hit {
+		var ids map[string]int
+		var err error
+
+		// uhttp would answer listing the teams again with the response cached for the first listing
+		if listed {
+			if err := uhttp.ClearCaches(ctx); err != nil {
+				ctxzap.Extract(ctx).Warn("dockerhub-connector: failed to clear http cache", zap.Error(err))
+			}
+		}
+
+		ids, rateLimitData, err = c.listTeamIds(ctx, orgSlug)
+		if err != nil {
+			return 0, rateLimitData, err
+		}
+
+		c.teams.store(orgSlug, ids)
+		id = ids[teamName]
+	}
+
+	hits, misses := c.teams.count(hit)
+	ctxzap.Extract(ctx).Debug("dockerhub-connector: resolved team id",
+		zap.String("org", orgSlug),
+		zap.String("team", teamName),
+		zap.Bool("cache_hit", hit),
+		zap.Int("cache_hits", hits),
+		zap.Int("cache_misses", misses),
+	)
+
+	if id == 0 {
+		return 0, rateLimitData, status.Errorf(codes.NotFound, "dockerhub: team %s not found in organization %s", teamName, orgSlug)
+	}
+
+	return id, rateLimitData, nil
+}
+
+func (c *Client) listTeamIds(ctx context.Context, orgSlug string) (map[string]int, *v2.RateLimitDescription, error) {
+	p := NewPaginator[Team](c, &PaginationVars{Size: teamCachePageSize}, TeamsEndpoint, orgSlug)
+
+	ids := make(map[string]int)
+	for team, err := range p.All(ctx) {
+		if err != nil {
+			return nil, p.RateLimit(), err
+		}
+
+		ids[team.Name] = team.Id
+	}
+
+	return ids, p.RateLimit(), nil
+}
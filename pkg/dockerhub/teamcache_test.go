@@ -0,0 +1,43 @@
+package dockerhub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTeamCacheExpires(t *testing.T) {
+	now := time.Now()
+	tc := newTeamCache(time.Minute)
+	tc.now = func() time.Time { return now }
+
+	if _, hit, _ := tc.lookup("acme", "developers"); hit {
+		t.Fatal("expected a miss before the teams are listed")
+	}
+
+	tc.store("acme", map[string]int{"developers": 1})
+
+	if id, hit, _ := tc.lookup("acme", "developers"); !hit || id != 1 {
+		t.Errorf("expected developers to resolve to 1, got %d (hit %v)", id, hit)
+	}
+	if _, hit, _ := tc.lookup("acme", "ops"); hit {
+		t.Error("expected an unknown team to be a miss, it may have been created since")
+	}
+	tc.store("acme", map[string]int{"developers": 1})
+	if id, hit, _ := tc.lookup("acme", "ops"); !hit || id != 0 {
+		t.Errorf("expected an unknown team to be a hit without id once listed again, got %d (hit %v)", id, hit)
+	}
+	if _, hit, _ := tc.lookup("globex", "developers"); hit {
+		t.Error("expected teams to be cached per organization")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, hit, _ := tc.lookup("acme", "developers"); hit {
+		t.Error("expected a miss once the TTL passed")
+	}
+
+	tc.store("acme", map[string]int{"developers": 1})
+	tc.invalidate("acme")
+	if _, hit, _ := tc.lookup("acme", "developers"); hit {
+		t.Error("expected a miss after invalidating the organization")
+	}
+}
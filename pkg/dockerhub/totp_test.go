@@ -0,0 +1,54 @@
+package dockerhub
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the base32 encoding of the SHA1 seed "12345678901234567890" of the RFC 6238 test vectors.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTP(t *testing.T) {
+	// RFC 6238 appendix B lists 8 digit codes, authenticator apps show their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := generateTOTP(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("expected code %s at %d, got %s", tt.want, tt.unix, got)
+		}
+	}
+}
+
+func TestGenerateTOTPSecretFormat(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	// secrets are often shown in lowercase groups of four, sometimes padded
+	for _, secret := range []string{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", rfc6238Secret + "===="} {
+		got, err := generateTOTP(secret, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "287082" {
+			t.Errorf("expected secret %q to give code 287082, got %s", secret, got)
+		}
+	}
+
+	if _, err := generateTOTP("not base32!", at); err == nil {
+		t.Error("expected an invalid secret to fail")
+	}
+}
@@ -0,0 +1,88 @@
+package dockerhub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// DefaultUserCacheTTL is how long the public profiles looked up by LookupUser are remembered.
+const DefaultUserCacheTTL = 5 * time.Minute
+
+// userCache remembers public user profiles by username. Collaborators and audit logs only name
+// the user, so without it every collaborator of every repository would take a request.
+type userCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mtx       sync.Mutex
+	users     map[string]cachedUser
+	lastSweep time.Time
+}
+
+type cachedUser struct {
+	user     *User
+	loadedAt time.Time
+}
+
+func newUserCache(ttl time.Duration) *userCache {
+	return &userCache{
+		ttl:   ttl,
+		now:   time.Now,
+		users: make(map[string]cachedUser),
+	}
+}
+
+// lookup returns the user if it was looked up within the TTL.
+func (uc *userCache) lookup(username string) *User {
+	uc.mtx.Lock()
+	defer uc.mtx.Unlock()
+
+	cached, ok := uc.users[username]
+	if !ok || uc.now().Sub(cached.loadedAt) > uc.ttl {
+		return nil
+	}
+
+	return cached.user
+}
+
+// store remembers the user, dropping the expired users once per TTL so the cache doesn't grow
+// with every user a long running connector ever looked up.
+func (uc *userCache) store(username string, user *User) {
+	uc.mtx.Lock()
+	defer uc.mtx.Unlock()
+
+	now := uc.now()
+	if now.Sub(uc.lastSweep) > uc.ttl {
+		for k, cached := range uc.users {
+			if now.Sub(cached.loadedAt) > uc.ttl {
+				delete(uc.users, k)
+			}
+		}
+		uc.lastSweep = now
+	}
+
+	uc.users[username] = cachedUser{user: user, loadedAt: now}
+}
+
+// LookupUser returns the public profile of the user like GetUser, remembering it for the TTL of the client.
+// Users that don't exist aren't remembered.
+func (c *Client) LookupUser(ctx context.Context, username string) (*User, *v2.RateLimitDescription, error) {
+	if user := c.users.lookup(username); user != nil {
+		ctxzap.Extract(ctx).Debug("dockerhub-connector: user cache hit", zap.String("username", username))
+		return user, nil, nil
+	}
+
+	user, rateLimitData, err := c.GetUser(ctx, username)
+	if err != nil {
+		return nil, rateLimitData, err
+	}
+
+	c.users.store(username, user)
+
+	return user, rateLimitData, nil
+}